@@ -0,0 +1,174 @@
+package account
+
+import (
+	"Backed/database/dal"
+	"Backed/database/model"
+	"Backed/utils"
+	"Backed/utils/accout"
+	"Backed/utils/audit"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	errorKey      = "error"
+	internalError = "服务器内部错误，请稍后再试"
+	inputError    = "输入格式错误，请重试"
+	invalidCreds  = "密码错误"
+)
+
+func AddExport(c *gin.Context) {
+	user, err := utils.GetCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{errorKey: "无法获取用户信息"})
+		return
+	}
+
+	// 同一时间只允许一个进行中的导出任务
+	var running int64
+	err = dal.PostgreSQL.Model(&model.DataExport{}).
+		Where("user_id = ? AND status IN ?", user.UserID, []string{model.ExportPending, model.ExportProcessing}).
+		Count(&running).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
+		return
+	}
+	if running > 0 {
+		c.JSON(http.StatusConflict, gin.H{errorKey: "已有正在进行的导出任务"})
+		return
+	}
+
+	export := model.DataExport{UserID: user.UserID, Status: model.ExportPending}
+	if err := dal.PostgreSQL.Create(&export).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "id": export.ID})
+}
+
+func GetExportList(c *gin.Context) {
+	user, err := utils.GetCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{errorKey: "无法获取用户信息"})
+		return
+	}
+
+	var exports []model.DataExport
+	err = dal.PostgreSQL.Where("user_id = ?", user.UserID).Order("id DESC").Find(&exports).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"exports": exports})
+}
+
+func DownloadExport(c *gin.Context) {
+	user, err := utils.GetCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{errorKey: "无法获取用户信息"})
+		return
+	}
+
+	exportID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{errorKey: inputError})
+		return
+	}
+
+	var export model.DataExport
+	err = dal.PostgreSQL.Where("id = ? AND user_id = ?", exportID, user.UserID).First(&export).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{errorKey: "该导出不存在"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
+		return
+	}
+	if export.Status != model.ExportDone || (export.ExpiresAt != nil && export.ExpiresAt.Before(time.Now())) {
+		c.JSON(http.StatusConflict, gin.H{errorKey: "导出文件尚未生成或已过期"})
+		return
+	}
+
+	c.FileAttachment(export.FilePath, fmt.Sprintf("%s-export-%d.zip", user.Username, export.ID))
+}
+
+func GetDeletion(c *gin.Context) {
+	user, err := utils.GetCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{errorKey: "无法获取用户信息"})
+		return
+	}
+
+	var deletion model.AccountDeletion
+	err = dal.PostgreSQL.Where("user_id = ?", user.UserID).First(&deletion).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusOK, gin.H{"pending": false})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"pending": true, "deletion": deletion})
+}
+
+func AddDeletion(c *gin.Context) {
+	user, err := utils.GetCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{errorKey: "无法获取用户信息"})
+		return
+	}
+
+	// 注销前需再次确认密码
+	match, err := utils.VerifyPassword(c.PostForm("password"), user.Password)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
+		return
+	}
+	if !match {
+		c.JSON(http.StatusUnauthorized, gin.H{errorKey: invalidCreds})
+		return
+	}
+
+	deletion, err := accout.RequestDeletion(user.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
+		return
+	}
+
+	audit.RecordFromContext(c, &user.UserID, audit.ActionAccountDeleteRequest, audit.TargetUser,
+		strconv.FormatInt(user.UserID, 10), "计划删除时间: "+deletion.ScheduledAt.Format(time.RFC3339))
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "deletion": deletion})
+}
+
+func CancelDeletion(c *gin.Context) {
+	user, err := utils.GetCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{errorKey: "无法获取用户信息"})
+		return
+	}
+
+	cancelled, err := accout.CancelDeletion(user.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
+		return
+	}
+	if !cancelled {
+		c.JSON(http.StatusNotFound, gin.H{errorKey: "没有待处理的注销申请"})
+		return
+	}
+
+	audit.RecordFromContext(c, &user.UserID, audit.ActionAccountDeleteCancel, audit.TargetUser, strconv.FormatInt(user.UserID, 10), "")
+
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
@@ -0,0 +1,74 @@
+package admin
+
+import (
+	"Backed/database/dal"
+	"Backed/database/model"
+	"Backed/utils/audit"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"time"
+)
+
+func ListAuditLogs(c *gin.Context) {
+	page, size := parsePage(c)
+
+	query := dal.PostgreSQL.Model(&model.AuditLog{})
+	if actor := c.Query("actor_id"); actor != "" {
+		query = query.Where("actor_id = ?", actor)
+	}
+	if action := c.Query("action"); action != "" {
+		query = query.Where("action = ?", action)
+	}
+	if targetType := c.Query("target_type"); targetType != "" {
+		query = query.Where("target_type = ?", targetType)
+	}
+	if target := c.Query("target_id"); target != "" {
+		query = query.Where("target_id = ?", target)
+	}
+	if ip := c.Query("ip"); ip != "" {
+		query = query.Where("ip = ?", ip)
+	}
+
+	// 时间范围使用 RFC3339 格式
+	for param, cond := range map[string]string{"from": "created_at >= ?", "to": "created_at < ?"} {
+		raw := c.Query(param)
+		if raw == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{errorKey: inputError})
+			return
+		}
+		query = query.Where(cond, t)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
+		return
+	}
+
+	var logs []model.AuditLog
+	err := query.Order("id DESC").Limit(size).Offset((page - 1) * size).Find(&logs).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"logs": logs, "total": total, "page": page, "size": size})
+}
+
+func VerifyAuditLogs(c *gin.Context) {
+	brokenID, err := audit.Verify()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
+		return
+	}
+
+	if brokenID != 0 {
+		c.JSON(http.StatusOK, gin.H{"intact": false, "broken_id": brokenID})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"intact": true})
+}
@@ -0,0 +1,46 @@
+package admin
+
+import (
+	"Backed/api/articles"
+	"Backed/database"
+	"Backed/utils"
+	"Backed/utils/audit"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+)
+
+func DeleteArticle(c *gin.Context) {
+	articlesDB, err := database.UseArticleData()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
+		return
+	}
+	defer articlesDB.Close()
+
+	articleID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{errorKey: inputError})
+		return
+	}
+
+	deleted, err := articlesDB.DeleteArticle(articleID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
+		return
+	}
+	if !deleted {
+		c.JSON(http.StatusNotFound, gin.H{errorKey: "该文章不存在"})
+		return
+	}
+
+	var actorID *int64
+	if admin, err := utils.GetCurrentUser(c); err == nil {
+		actorID = &admin.UserID
+	}
+	audit.RecordFromContext(c, actorID, audit.ActionArticleDelete, audit.TargetArticle, strconv.FormatInt(articleID, 10), "管理员删除")
+
+	articles.CleanupArticle(articleID)
+
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
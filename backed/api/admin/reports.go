@@ -0,0 +1,93 @@
+package admin
+
+import (
+	"Backed/database/dal"
+	"Backed/database/model"
+	"Backed/utils"
+	"Backed/utils/audit"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func ListReports(c *gin.Context) {
+	page, size := parsePage(c)
+
+	query := dal.PostgreSQL.Model(&model.Report{})
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if target := c.Query("target_id"); target != "" {
+		query = query.Where("target_id = ?", target)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
+		return
+	}
+
+	var reports []model.Report
+	err := query.Order("created_at ASC").Limit(size).Offset((page - 1) * size).Find(&reports).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"reports": reports, "total": total, "page": page, "size": size})
+}
+
+func HandleReport(c *gin.Context) {
+	admin, err := utils.GetCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{errorKey: "无法获取用户信息"})
+		return
+	}
+
+	reportID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{errorKey: inputError})
+		return
+	}
+
+	status := c.PostForm("status")
+	if status != model.ReportResolved && status != model.ReportRejected {
+		c.JSON(http.StatusBadRequest, gin.H{errorKey: inputError})
+		return
+	}
+
+	var report model.Report
+	err = dal.PostgreSQL.Where("id = ?", reportID).First(&report).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{errorKey: "该举报不存在"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
+		return
+	}
+	if report.Status != model.ReportPending {
+		c.JSON(http.StatusConflict, gin.H{errorKey: "该举报已处理"})
+		return
+	}
+
+	now := time.Now()
+	err = dal.PostgreSQL.Model(&report).Updates(map[string]interface{}{
+		"status":      status,
+		"handle_note": strings.TrimSpace(c.PostForm("note")),
+		"handled_by":  admin.UserID,
+		"handled_at":  now,
+	}).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
+		return
+	}
+
+	audit.RecordFromContext(c, &admin.UserID, audit.ActionReportHandle, audit.TargetReport, strconv.FormatInt(report.ID, 10), status)
+
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
@@ -0,0 +1,227 @@
+package admin
+
+import (
+	"Backed/database/dal"
+	"Backed/database/model"
+	"Backed/utils"
+	"Backed/utils/audit"
+	"Backed/utils/realtime"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	errorKey      = "error"
+	internalError = "服务器内部错误，请稍后再试"
+	inputError    = "输入格式错误，请重试"
+	userNotFound  = "用户不存在"
+
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+type userView struct {
+	UserID      int64      `json:"user_id,string"`
+	Username    string     `json:"username"`
+	Email       string     `json:"email"`
+	IsActive    bool       `json:"is_active"`
+	IsAdmin     bool       `json:"is_admin"`
+	IsBanned    bool       `json:"is_banned"`
+	BanReason   string     `json:"ban_reason"`
+	BannedUntil *time.Time `json:"banned_until"`
+	CreatedAt   time.Time  `json:"created_at"`
+	LastLogin   *time.Time `json:"last_login"`
+}
+
+func newUserView(u *model.User) userView {
+	return userView{
+		UserID:      u.UserID,
+		Username:    u.Username,
+		Email:       u.Email,
+		IsActive:    u.IsActive,
+		IsAdmin:     u.IsAdmin,
+		IsBanned:    u.IsBanActive(time.Now()),
+		BanReason:   u.BanReason,
+		BannedUntil: u.BannedUntil,
+		CreatedAt:   u.CreatedAt,
+		LastLogin:   u.LastLogin,
+	}
+}
+
+// parsePage 解析分页参数
+func parsePage(c *gin.Context) (page, size int) {
+	page, err := strconv.Atoi(c.Query("page"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	size, err = strconv.Atoi(c.Query("size"))
+	if err != nil || size < 1 {
+		size = defaultPageSize
+	}
+	if size > maxPageSize {
+		size = maxPageSize
+	}
+	return page, size
+}
+
+func ListUsers(c *gin.Context) {
+	page, size := parsePage(c)
+
+	query := dal.PostgreSQL.Model(&model.User{})
+	if q := strings.TrimSpace(c.Query("q")); q != "" {
+		like := "%" + q + "%"
+		query = query.Where("username ILIKE ? OR email ILIKE ?", like, like)
+	}
+	if c.Query("banned") == "true" {
+		// 已过期的封禁不再列出
+		query = query.Where("is_banned = ? AND (banned_until IS NULL OR banned_until > ?)", true, time.Now())
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
+		return
+	}
+
+	var users []model.User
+	err := query.Order("created_at DESC").Limit(size).Offset((page - 1) * size).Find(&users).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
+		return
+	}
+
+	views := make([]userView, 0, len(users))
+	for i := range users {
+		views = append(views, newUserView(&users[i]))
+	}
+
+	c.JSON(http.StatusOK, gin.H{"users": views, "total": total, "page": page, "size": size})
+}
+
+func BanUser(c *gin.Context) {
+	user, ok := findUser(c)
+	if !ok {
+		return
+	}
+	if user.IsAdmin {
+		c.JSON(http.StatusBadRequest, gin.H{errorKey: "不能封禁管理员"})
+		return
+	}
+
+	reason := strings.TrimSpace(c.PostForm("reason"))
+	if reason == "" {
+		c.JSON(http.StatusBadRequest, gin.H{errorKey: "请填写封禁理由"})
+		return
+	}
+
+	// 封禁时长(小时)，留空或为0表示永久封禁
+	var bannedUntil *time.Time
+	if raw := c.PostForm("hours"); raw != "" {
+		hours, err := strconv.Atoi(raw)
+		if err != nil || hours < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{errorKey: inputError})
+			return
+		}
+		if hours > 0 {
+			until := time.Now().Add(time.Duration(hours) * time.Hour)
+			bannedUntil = &until
+		}
+	}
+
+	// 封禁同时让已签发的令牌失效
+	now := time.Now()
+	err := dal.PostgreSQL.Model(user).Updates(map[string]interface{}{
+		"is_banned":          true,
+		"ban_reason":         reason,
+		"banned_until":       bannedUntil,
+		"tokens_valid_after": now,
+	}).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
+		return
+	}
+
+	detail := "永久封禁: " + reason
+	if bannedUntil != nil {
+		detail = fmt.Sprintf("封禁至 %s: %s", bannedUntil.Format(time.RFC3339), reason)
+	}
+	recordAction(c, audit.ActionUserBan, user.UserID, detail)
+	realtime.DisconnectUser(user.UserID)
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "banned_until": bannedUntil})
+}
+
+func UnbanUser(c *gin.Context) {
+	user, ok := findUser(c)
+	if !ok {
+		return
+	}
+
+	err := dal.PostgreSQL.Model(user).Updates(map[string]interface{}{
+		"is_banned":    false,
+		"ban_reason":   "",
+		"banned_until": nil,
+	}).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
+		return
+	}
+
+	recordAction(c, audit.ActionUserUnban, user.UserID, "")
+
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
+
+func ForceLogout(c *gin.Context) {
+	user, ok := findUser(c)
+	if !ok {
+		return
+	}
+
+	err := dal.PostgreSQL.Model(user).Update("tokens_valid_after", time.Now()).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
+		return
+	}
+
+	recordAction(c, audit.ActionForceLogout, user.UserID, "")
+	realtime.DisconnectUser(user.UserID)
+
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
+
+// recordAction 以当前管理员身份记录针对用户的审计日志
+func recordAction(c *gin.Context, action string, targetID int64, detail string) {
+	var actorID *int64
+	if admin, err := utils.GetCurrentUser(c); err == nil {
+		actorID = &admin.UserID
+	}
+	audit.RecordFromContext(c, actorID, action, audit.TargetUser, strconv.FormatInt(targetID, 10), detail)
+}
+
+// findUser 根据路由参数中的用户ID查询用户，失败时直接写入响应
+func findUser(c *gin.Context) (*model.User, bool) {
+	userID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{errorKey: inputError})
+		return nil, false
+	}
+
+	var user model.User
+	err = dal.PostgreSQL.Where("user_id = ?", userID).First(&user).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{errorKey: userNotFound})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
+		return nil, false
+	}
+	return &user, true
+}
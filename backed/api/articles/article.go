@@ -3,19 +3,62 @@ package articles
 import (
 	"Backed/database"
 	"Backed/utils"
+	"Backed/utils/audit"
+	"Backed/utils/markdown"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"github.com/gin-gonic/gin"
+	"log"
 	"net/http"
 	"strconv"
-	"strings"
 )
 
 const (
 	errorKey       = "error"
 	internalError  = "服务器内部错误，请稍后再试"
 	duplicateError = "该名称已被占用，请换一个吧!"
+
+	maxContentLength = 200000
+	defaultPageSize  = 20
+	maxPageSize      = 100
 )
 
 func GetList(c *gin.Context) {
+	filter := database.ArticleFilter{
+		Category: c.Query("category"),
+		Tag:      c.Query("tag"),
+		Author:   c.Query("author"),
+		Sort:     c.DefaultQuery("sort", database.SortNewest),
+	}
+	switch filter.Sort {
+	case database.SortNewest, database.SortMostViewed, database.SortMostLiked:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{errorKey: "不支持的排序方式"})
+		return
+	}
+	if raw := c.Query("featured"); raw != "" {
+		featured, err := strconv.ParseBool(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{errorKey: "输入格式错误，请重试"})
+			return
+		}
+		filter.FeaturedOnly = featured
+	}
+
+	page, pageSize := parsePage(c)
+
+	// 游标分页在翻页期间有新文章发布时不会重复或遗漏
+	var cursor *database.ArticleCursor
+	if raw := c.Query("cursor"); raw != "" {
+		var err error
+		cursor, err = decodeCursor(raw)
+		if err != nil || cursor.Sort != filter.Sort {
+			c.JSON(http.StatusBadRequest, gin.H{errorKey: "无效的分页游标"})
+			return
+		}
+	}
+
 	articlesDB, err := database.UseArticleData()
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
@@ -23,12 +66,69 @@ func GetList(c *gin.Context) {
 	}
 	defer articlesDB.Close()
 
-	articles, err := articlesDB.ListArticles(10000, 0, "", "", false)
+	articles, err := articlesDB.ListArticles(filter, pageSize, (page-1)*pageSize, cursor)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
+		return
+	}
+	total, err := articlesDB.CountArticles(filter)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"articles": articles})
+
+	nextCursor := ""
+	if len(articles) == pageSize {
+		nextCursor = encodeCursor(filter.CursorAfter(articles[len(articles)-1]))
+	}
+	if articles == nil {
+		articles = []*database.Article{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"articles":    articles,
+		"total":       total,
+		"page":        page,
+		"page_size":   pageSize,
+		"next_cursor": nextCursor,
+	})
+}
+
+// encodeCursor 游标对客户端不透明
+func encodeCursor(cursor *database.ArticleCursor) string {
+	raw, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func decodeCursor(encoded string) (*database.ArticleCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, err
+	}
+	var cursor database.ArticleCursor
+	if err := json.Unmarshal(raw, &cursor); err != nil {
+		return nil, err
+	}
+	if cursor.ID <= 0 {
+		return nil, errors.New("游标缺少文章ID")
+	}
+	return &cursor, nil
+}
+
+// parsePage 读取分页参数，非法值使用默认值
+func parsePage(c *gin.Context) (int, int) {
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	pageSize, err := strconv.Atoi(c.DefaultQuery("page_size", strconv.Itoa(defaultPageSize)))
+	if err != nil || pageSize < 1 {
+		pageSize = defaultPageSize
+	}
+	if pageSize > maxPageSize {
+		pageSize = maxPageSize
+	}
+	return page, pageSize
 }
 
 func AddArticle(c *gin.Context) {
@@ -54,25 +154,23 @@ func AddArticle(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{errorKey: "输入格式错误，请重试"})
 		return
 	}
-	formData.Likes, err = strconv.Atoi(c.PostForm("likes"))
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{errorKey: "输入格式错误，请重试"})
-		return
-	}
-	formData.Comments, err = strconv.Atoi(c.PostForm("comments"))
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{errorKey: "输入格式错误，请重试"})
+	// 点赞、评论和浏览数只由服务端统计，新文章从零开始
+	formData.Category = c.PostForm("category")
+	formData.Tags = parseTags(c.PostForm("tags"))
+	formData.Featured, err = strconv.ParseBool(c.PostForm("featured"))
+
+	// 正文为 Markdown，保存时同时渲染一份清洗后的 HTML
+	formData.Content = c.PostForm("content")
+	if len([]rune(formData.Content)) > maxContentLength {
+		c.JSON(http.StatusBadRequest, gin.H{errorKey: "正文过长"})
 		return
 	}
-	formData.Views, err = strconv.Atoi(c.PostForm("views"))
+	formData.ContentHTML, err = markdown.Render(formData.Content)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{errorKey: "输入格式错误，请重试"})
+		c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
 		return
 	}
-	formData.Category = c.PostForm("category")
-	tags := c.PostForm("tags")
-	formData.Tags = strings.Split(tags, ",")
-	formData.Featured, err = strconv.ParseBool(c.PostForm("featured"))
+
 	article, err := articlesDB.InsertArticle(&formData)
 	if err != nil {
 		if articlesDB.IsDuplicateError(err) {
@@ -84,6 +182,16 @@ func AddArticle(c *gin.Context) {
 		return
 	}
 
+	// 发布时的内容作为第一个修订版本
+	revisionsDB, err := database.UseRevisionData()
+	if err == nil {
+		defer revisionsDB.Close()
+		_, err = revisionsDB.AddRevision(article, article.Author, nil)
+	}
+	if err != nil {
+		log.Printf("保存文章 %d 的初始版本失败: %s", article.ID, err)
+	}
+
 	c.JSON(http.StatusOK, gin.H{"success": true, "id": article.ID})
 }
 
@@ -126,11 +234,26 @@ func DeleteArticle(c *gin.Context) {
 			c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
 			return
 		}
+
+		var actorID *int64
+		if user, err := utils.GetCurrentUser(c); err == nil {
+			actorID = &user.UserID
+		}
+		audit.RecordFromContext(c, actorID, audit.ActionArticleDelete, audit.TargetArticle, strconv.Itoa(articleID), "")
+
+		CleanupArticle(int64(articleID))
 	} else {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "这篇文章不是你的哦"})
 	}
 }
 
+// CleanupArticle 文章删除后清理其修订历史、表情回应、点赞收藏和评论，失败只记录日志
+func CleanupArticle(articleID int64) {
+	if err := database.DeleteArticlesData([]int64{articleID}); err != nil {
+		log.Printf("清理文章 %d 的关联数据失败: %s", articleID, err)
+	}
+}
+
 func GetArticle(c *gin.Context) {
 	articlesDB, err := database.UseArticleData()
 	if err != nil {
@@ -158,5 +281,26 @@ func GetArticle(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"article": article})
+	recordView(c, articlesDB, article)
+	resp := gin.H{"article": article}
+
+	// 带有登录令牌时附带自己的点赞和收藏状态
+	if user, err := utils.GetCurrentUser(c); err == nil {
+		engagementDB, err := database.UseEngagementData()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
+			return
+		}
+		defer engagementDB.Close()
+
+		liked, bookmarked, err := engagementDB.UserState(article.ID, user.UserID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
+			return
+		}
+		resp["liked"] = liked
+		resp["bookmarked"] = bookmarked
+	}
+
+	c.JSON(http.StatusOK, resp)
 }
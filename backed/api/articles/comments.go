@@ -0,0 +1,301 @@
+package articles
+
+import (
+	"Backed/database"
+	"Backed/database/dal"
+	"Backed/database/model"
+	"Backed/utils"
+	"Backed/utils/audit"
+	"database/sql"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const maxCommentLength = 5000
+
+type commentView struct {
+	*database.Comment
+	Username string `json:"username"`
+}
+
+func GetComments(c *gin.Context) {
+	articleID, ok := parseArticleID(c)
+	if !ok {
+		return
+	}
+	page, pageSize := parsePage(c)
+
+	commentsDB, err := database.UseCommentData()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
+		return
+	}
+	defer commentsDB.Close()
+
+	comments, total, err := commentsDB.ListComments(articleID, pageSize, (page-1)*pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
+		return
+	}
+	views, err := withUsernames(comments)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"comments": views, "total": total, "page": page, "page_size": pageSize})
+}
+
+// GetReplies 路由中的ID为顶层评论
+func GetReplies(c *gin.Context) {
+	rootID, ok := parseIDParam(c)
+	if !ok {
+		return
+	}
+	page, pageSize := parsePage(c)
+
+	commentsDB, err := database.UseCommentData()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
+		return
+	}
+	defer commentsDB.Close()
+
+	replies, total, err := commentsDB.ListReplies(rootID, pageSize, (page-1)*pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
+		return
+	}
+	views, err := withUsernames(replies)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"replies": views, "total": total, "page": page, "page_size": pageSize})
+}
+
+func AddComment(c *gin.Context) {
+	user, err := utils.GetCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{errorKey: "无法获取用户信息"})
+		return
+	}
+
+	articleID, ok := parseArticleID(c)
+	if !ok {
+		return
+	}
+	content, ok := commentContent(c)
+	if !ok {
+		return
+	}
+
+	comment := &database.Comment{ArticleID: articleID, UserID: user.UserID, Content: content}
+	if raw := c.PostForm("parent_id"); raw != "" {
+		parentID, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{errorKey: "输入格式错误，请重试"})
+			return
+		}
+		comment.ParentID = &parentID
+	}
+
+	if !articleExists(c, articleID) {
+		return
+	}
+
+	commentsDB, err := database.UseCommentData()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
+		return
+	}
+	defer commentsDB.Close()
+
+	comment, err = commentsDB.AddComment(comment)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{errorKey: "回复的评论不存在"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "comment": commentView{Comment: comment, Username: user.Username}})
+}
+
+// EditComment 只有评论者本人可以修改评论
+func EditComment(c *gin.Context) {
+	user, err := utils.GetCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{errorKey: "无法获取用户信息"})
+		return
+	}
+
+	commentID, ok := parseIDParam(c)
+	if !ok {
+		return
+	}
+	content, ok := commentContent(c)
+	if !ok {
+		return
+	}
+
+	commentsDB, err := database.UseCommentData()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
+		return
+	}
+	defer commentsDB.Close()
+
+	comment, ok := loadComment(c, commentsDB, commentID)
+	if !ok {
+		return
+	}
+	if comment.UserID != user.UserID {
+		c.JSON(http.StatusForbidden, gin.H{errorKey: "这条评论不是你的哦"})
+		return
+	}
+
+	comment.EditedAt, err = commentsDB.UpdateComment(commentID, content)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{errorKey: "该评论不存在"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
+		return
+	}
+	comment.Content = content
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "comment": commentView{Comment: comment, Username: user.Username}})
+}
+
+// DeleteComment 评论者本人、文章作者和管理员可以删除评论
+func DeleteComment(c *gin.Context) {
+	user, err := utils.GetCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{errorKey: "无法获取用户信息"})
+		return
+	}
+
+	commentID, ok := parseIDParam(c)
+	if !ok {
+		return
+	}
+
+	commentsDB, err := database.UseCommentData()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
+		return
+	}
+	defer commentsDB.Close()
+
+	comment, ok := loadComment(c, commentsDB, commentID)
+	if !ok {
+		return
+	}
+
+	if comment.UserID != user.UserID {
+		articlesDB, err := database.UseArticleData()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
+			return
+		}
+		article, err := articlesDB.GetArticleByID(comment.ArticleID)
+		articlesDB.Close()
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
+			return
+		}
+		if !user.IsAdmin && (article == nil || article.Author != user.Username) {
+			c.JSON(http.StatusForbidden, gin.H{errorKey: "这条评论不是你的哦"})
+			return
+		}
+	}
+
+	changed, err := commentsDB.DeleteComment(commentID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{errorKey: "该评论不存在"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
+		return
+	}
+
+	// 删除他人评论属于管理操作，需要留痕
+	if changed && comment.UserID != user.UserID {
+		detail := "文章 " + strconv.FormatInt(comment.ArticleID, 10)
+		audit.RecordFromContext(c, &user.UserID, audit.ActionCommentDelete, audit.TargetComment, strconv.FormatInt(commentID, 10), detail)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "changed": changed})
+}
+
+// loadComment 读取评论，失败时直接写入响应
+func loadComment(c *gin.Context, commentsDB *database.CommentData, commentID int64) (*database.Comment, bool) {
+	comment, err := commentsDB.GetComment(commentID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{errorKey: "该评论不存在"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
+		return nil, false
+	}
+	if comment.Deleted {
+		c.JSON(http.StatusNotFound, gin.H{errorKey: "该评论已被删除"})
+		return nil, false
+	}
+	return comment, true
+}
+
+// commentContent 读取并校验评论内容，失败时直接写入响应
+func commentContent(c *gin.Context) (string, bool) {
+	content := strings.TrimSpace(c.PostForm("content"))
+	if content == "" {
+		c.JSON(http.StatusBadRequest, gin.H{errorKey: "评论内容不能为空"})
+		return "", false
+	}
+	if utf8.RuneCountInString(content) > maxCommentLength {
+		c.JSON(http.StatusBadRequest, gin.H{errorKey: "评论内容过长"})
+		return "", false
+	}
+	return content, true
+}
+
+// withUsernames 评论只保存用户ID，用户名从用户表补全；已删除的评论不显示作者
+func withUsernames(comments []*database.Comment) ([]commentView, error) {
+	ids := make([]int64, 0, len(comments))
+	for _, comment := range comments {
+		if !comment.Deleted {
+			ids = append(ids, comment.UserID)
+		}
+	}
+	var users []model.User
+	if len(ids) > 0 {
+		err := dal.PostgreSQL.Select("user_id", "username").Where("user_id IN ?", ids).Find(&users).Error
+		if err != nil {
+			return nil, err
+		}
+	}
+	names := make(map[int64]string, len(users))
+	for _, u := range users {
+		names[u.UserID] = u.Username
+	}
+
+	views := make([]commentView, 0, len(comments))
+	for _, comment := range comments {
+		if comment.Deleted {
+			comment.UserID = 0
+		}
+		views = append(views, commentView{Comment: comment, Username: names[comment.UserID]})
+	}
+	return views, nil
+}
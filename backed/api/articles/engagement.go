@@ -0,0 +1,165 @@
+package articles
+
+import (
+	"Backed/database"
+	"Backed/utils"
+	"github.com/gin-gonic/gin"
+	"log"
+	"net/http"
+)
+
+func LikeArticle(c *gin.Context) {
+	changeLike(c, true)
+}
+
+func UnlikeArticle(c *gin.Context) {
+	changeLike(c, false)
+}
+
+// changeLike 点赞和取消点赞都是幂等的，重复请求只返回当前状态
+func changeLike(c *gin.Context, like bool) {
+	user, err := utils.GetCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{errorKey: "无法获取用户信息"})
+		return
+	}
+
+	articleID, ok := parseArticleID(c)
+	if !ok {
+		return
+	}
+	if like && !articleExists(c, articleID) {
+		return
+	}
+
+	engagementDB, err := database.UseEngagementData()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
+		return
+	}
+	defer engagementDB.Close()
+
+	var changed bool
+	if like {
+		changed, err = engagementDB.Like(articleID, user.UserID)
+	} else {
+		changed, err = engagementDB.Unlike(articleID, user.UserID)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
+		return
+	}
+
+	likes, err := engagementDB.GetLikes(articleID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "changed": changed, "liked": like, "likes": likes})
+}
+
+func AddBookmark(c *gin.Context) {
+	changeBookmark(c, true)
+}
+
+func DeleteBookmark(c *gin.Context) {
+	changeBookmark(c, false)
+}
+
+func changeBookmark(c *gin.Context, add bool) {
+	user, err := utils.GetCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{errorKey: "无法获取用户信息"})
+		return
+	}
+
+	articleID, ok := parseArticleID(c)
+	if !ok {
+		return
+	}
+	if add && !articleExists(c, articleID) {
+		return
+	}
+
+	engagementDB, err := database.UseEngagementData()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
+		return
+	}
+	defer engagementDB.Close()
+
+	var changed bool
+	if add {
+		changed, err = engagementDB.AddBookmark(articleID, user.UserID)
+	} else {
+		changed, err = engagementDB.RemoveBookmark(articleID, user.UserID)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "changed": changed, "bookmarked": add})
+}
+
+func GetBookmarks(c *gin.Context) {
+	user, err := utils.GetCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{errorKey: "无法获取用户信息"})
+		return
+	}
+
+	page, pageSize := parsePage(c)
+
+	engagementDB, err := database.UseEngagementData()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
+		return
+	}
+	defer engagementDB.Close()
+
+	articles, total, err := engagementDB.ListBookmarks(user.UserID, pageSize, (page-1)*pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
+		return
+	}
+	if articles == nil {
+		articles = []*database.Article{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"articles":  articles,
+		"total":     total,
+		"page":      page,
+		"page_size": pageSize,
+	})
+}
+
+// recordView 统计一次浏览，同一用户或 IP 在窗口期内只计一次，作者本人的浏览不计入；失败只记录日志
+func recordView(c *gin.Context, articlesDB *database.ArticleData, article *database.Article) {
+	viewer := database.IPViewer(c.ClientIP())
+	if user, err := utils.GetCurrentUser(c); err == nil {
+		if user.Username == article.Author {
+			return
+		}
+		viewer = database.UserViewer(user.UserID)
+	}
+
+	engagementDB, err := database.UseEngagementData()
+	if err != nil {
+		log.Printf("记录文章 %d 的浏览失败: %s", article.ID, err)
+		return
+	}
+	defer engagementDB.Close()
+
+	counted, err := engagementDB.RecordView(article.ID, viewer)
+	if err == nil && counted {
+		if err = articlesDB.IncrementViews(article.ID); err == nil {
+			article.Views++
+		}
+	}
+	if err != nil {
+		log.Printf("记录文章 %d 的浏览失败: %s", article.ID, err)
+	}
+}
@@ -0,0 +1,244 @@
+package articles
+
+import (
+	"Backed/database"
+	"Backed/database/dal"
+	"Backed/database/model"
+	"Backed/utils"
+	"Backed/utils/realtime"
+	"database/sql"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+	"unicode/utf8"
+)
+
+const (
+	// TopicPrefix 文章实时主题的前缀，打开文章页面的客户端订阅 "article:<ID>"
+	TopicPrefix = "article:"
+
+	maxReactionsPerUser = 20
+	maxEmojiRunes       = 8
+	reactorsPageSize    = 50
+)
+
+func AddReaction(c *gin.Context) {
+	changeReaction(c, true)
+}
+
+func DeleteReaction(c *gin.Context) {
+	changeReaction(c, false)
+}
+
+func changeReaction(c *gin.Context, add bool) {
+	user, err := utils.GetCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{errorKey: "无法获取用户信息"})
+		return
+	}
+
+	articleID, ok := parseArticleID(c)
+	if !ok {
+		return
+	}
+	emoji := c.PostForm("emoji")
+	if !validEmoji(emoji) {
+		c.JSON(http.StatusBadRequest, gin.H{errorKey: "无效的表情"})
+		return
+	}
+
+	reactionsDB, err := database.UseReactionData()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
+		return
+	}
+	defer reactionsDB.Close()
+
+	var changed bool
+	if add {
+		if !articleExists(c, articleID) {
+			return
+		}
+
+		count, err := reactionsDB.CountUserReactions(articleID, user.UserID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
+			return
+		}
+		if count >= maxReactionsPerUser {
+			c.JSON(http.StatusBadRequest, gin.H{errorKey: "表情回应数量已达上限"})
+			return
+		}
+
+		changed, err = reactionsDB.AddReaction(articleID, user.UserID, emoji)
+	} else {
+		changed, err = reactionsDB.RemoveReaction(articleID, user.UserID, emoji)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
+		return
+	}
+
+	counts, err := reactionsDB.GetCounts(articleID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
+		return
+	}
+
+	if changed {
+		realtime.Publish(articleTopic(articleID), realtime.NewEvent("article.reaction", realtime.H{
+			"article_id": articleID,
+			"reactions":  counts,
+		}))
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "changed": changed, "reactions": counts})
+}
+
+func articleTopic(articleID int64) string {
+	return TopicPrefix + strconv.FormatInt(articleID, 10)
+}
+
+func GetReactions(c *gin.Context) {
+	articleID, ok := parseArticleID(c)
+	if !ok {
+		return
+	}
+
+	reactionsDB, err := database.UseReactionData()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
+		return
+	}
+	defer reactionsDB.Close()
+
+	counts, err := reactionsDB.GetCounts(articleID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
+		return
+	}
+	resp := gin.H{"reactions": counts}
+
+	// 带有登录令牌时附带自己的回应
+	if user, err := utils.GetCurrentUser(c); err == nil {
+		mine, err := reactionsDB.GetUserReactions(articleID, user.UserID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
+			return
+		}
+		resp["mine"] = mine
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+type reactorView struct {
+	database.Reactor
+	Username string `json:"username"`
+}
+
+func GetReactors(c *gin.Context) {
+	articleID, ok := parseArticleID(c)
+	if !ok {
+		return
+	}
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{errorKey: "输入格式错误，请重试"})
+		return
+	}
+
+	reactionsDB, err := database.UseReactionData()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
+		return
+	}
+	defer reactionsDB.Close()
+
+	reactors, err := reactionsDB.ListReactors(articleID, c.Query("emoji"), reactorsPageSize, (page-1)*reactorsPageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
+		return
+	}
+
+	// 回应记录只保存用户ID，用户名从用户表补全
+	ids := make([]int64, 0, len(reactors))
+	for _, r := range reactors {
+		ids = append(ids, r.UserID)
+	}
+	var users []model.User
+	if len(ids) > 0 {
+		err = dal.PostgreSQL.Select("user_id", "username").Where("user_id IN ?", ids).Find(&users).Error
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
+			return
+		}
+	}
+	names := make(map[int64]string, len(users))
+	for _, u := range users {
+		names[u.UserID] = u.Username
+	}
+
+	views := make([]reactorView, 0, len(reactors))
+	for _, r := range reactors {
+		views = append(views, reactorView{Reactor: r, Username: names[r.UserID]})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"reactors": views, "page": page})
+}
+
+// parseArticleID 解析路由中的文章ID，失败时直接写入响应
+func parseArticleID(c *gin.Context) (int64, bool) {
+	return parseIDParam(c)
+}
+
+// parseIDParam 解析路由中的 id 参数，文章和评论的路由共用；失败时直接写入响应
+func parseIDParam(c *gin.Context) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{errorKey: "输入格式错误，请重试"})
+		return 0, false
+	}
+	return id, true
+}
+
+// articleExists 检查文章是否存在，失败时直接写入响应
+func articleExists(c *gin.Context, articleID int64) bool {
+	articlesDB, err := database.UseArticleData()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
+		return false
+	}
+	defer articlesDB.Close()
+
+	_, err = articlesDB.GetArticleByID(articleID)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{errorKey: "该文章不存在"})
+		return false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
+		return false
+	}
+	return true
+}
+
+// validEmoji 只接受由表情符号组成的短字符串，不允许普通文字
+func validEmoji(emoji string) bool {
+	if emoji == "" || len(emoji) > 32 || !utf8.ValidString(emoji) {
+		return false
+	}
+	count, hasSymbol := 0, false
+	for _, r := range emoji {
+		count++
+		switch {
+		case r >= 0x2000 || r == 0x00A9 || r == 0x00AE:
+			hasSymbol = true
+		case r == '#' || r == '*' || (r >= '0' && r <= '9'): // 键帽表情的组成部分
+		default:
+			return false
+		}
+	}
+	return hasSymbol && count <= maxEmojiRunes
+}
@@ -0,0 +1,338 @@
+package articles
+
+import (
+	"Backed/database"
+	"Backed/database/model"
+	"Backed/utils"
+	"Backed/utils/audit"
+	"Backed/utils/diff"
+	"Backed/utils/markdown"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+func UpdateArticle(c *gin.Context) {
+	articlesDB, err := database.UseArticleData()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
+		return
+	}
+	defer articlesDB.Close()
+
+	article, user, ok := loadEditableArticle(c, articlesDB)
+	if !ok {
+		return
+	}
+	original := *article
+
+	// 只修改提交了的字段
+	if title, ok := c.GetPostForm("title"); ok {
+		title = strings.TrimSpace(title)
+		if title == "" {
+			c.JSON(http.StatusBadRequest, gin.H{errorKey: "标题不能为空"})
+			return
+		}
+		article.Title = title
+	}
+	if excerpt, ok := c.GetPostForm("excerpt"); ok {
+		article.Excerpt = excerpt
+	}
+	if category, ok := c.GetPostForm("category"); ok {
+		article.Category = category
+	}
+	if tags, ok := c.GetPostForm("tags"); ok {
+		article.Tags = parseTags(tags)
+	}
+	if raw, ok := c.GetPostForm("read_time"); ok {
+		article.ReadTime, err = strconv.Atoi(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{errorKey: "输入格式错误，请重试"})
+			return
+		}
+	}
+	if content, ok := c.GetPostForm("content"); ok {
+		if len([]rune(content)) > maxContentLength {
+			c.JSON(http.StatusBadRequest, gin.H{errorKey: "正文过长"})
+			return
+		}
+		article.Content = content
+		article.ContentHTML, err = markdown.Render(content)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
+			return
+		}
+	}
+
+	revision, ok := saveWithRevision(c, articlesDB, &original, article, user, nil)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "article": article, "revision": revision})
+}
+
+func GetRevisions(c *gin.Context) {
+	articlesDB, err := database.UseArticleData()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
+		return
+	}
+	defer articlesDB.Close()
+
+	article, _, ok := loadEditableArticle(c, articlesDB)
+	if !ok {
+		return
+	}
+
+	revisionsDB, err := database.UseRevisionData()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
+		return
+	}
+	defer revisionsDB.Close()
+
+	revisions, err := revisionsDB.ListRevisions(article.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"revisions": revisions})
+}
+
+func GetRevision(c *gin.Context) {
+	articlesDB, err := database.UseArticleData()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
+		return
+	}
+	defer articlesDB.Close()
+
+	article, _, ok := loadEditableArticle(c, articlesDB)
+	if !ok {
+		return
+	}
+
+	revisionsDB, err := database.UseRevisionData()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
+		return
+	}
+	defer revisionsDB.Close()
+
+	revision, ok := findRevision(c, revisionsDB, article.ID, c.Query("revision"))
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"revision": revision})
+}
+
+func DiffRevisions(c *gin.Context) {
+	articlesDB, err := database.UseArticleData()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
+		return
+	}
+	defer articlesDB.Close()
+
+	article, _, ok := loadEditableArticle(c, articlesDB)
+	if !ok {
+		return
+	}
+
+	revisionsDB, err := database.UseRevisionData()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
+		return
+	}
+	defer revisionsDB.Close()
+
+	from, ok := findRevision(c, revisionsDB, article.ID, c.Query("from"))
+	if !ok {
+		return
+	}
+	to, ok := findRevision(c, revisionsDB, article.ID, c.Query("to"))
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"from":    from.Revision,
+		"to":      to.Revision,
+		"title":   gin.H{"from": from.Title, "to": to.Title, "changed": from.Title != to.Title},
+		"excerpt": diff.Lines(from.Excerpt, to.Excerpt),
+		"content": diff.Lines(from.Content, to.Content),
+		"tags": gin.H{
+			"added":   tagsMissing(to.Tags, from.Tags),
+			"removed": tagsMissing(from.Tags, to.Tags),
+		},
+	})
+}
+
+func RestoreRevision(c *gin.Context) {
+	articlesDB, err := database.UseArticleData()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
+		return
+	}
+	defer articlesDB.Close()
+
+	article, user, ok := loadEditableArticle(c, articlesDB)
+	if !ok {
+		return
+	}
+	original := *article
+
+	revisionsDB, err := database.UseRevisionData()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
+		return
+	}
+	restored, ok := findRevision(c, revisionsDB, article.ID, c.PostForm("revision"))
+	revisionsDB.Close()
+	if !ok {
+		return
+	}
+
+	article.Title = restored.Title
+	article.Excerpt = restored.Excerpt
+	article.Tags = restored.Tags
+	article.Content = restored.Content
+	article.ContentHTML, err = markdown.Render(restored.Content)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
+		return
+	}
+
+	// 恢复同样产生一个新版本，历史记录只增不改
+	revision, ok := saveWithRevision(c, articlesDB, &original, article, user, &restored.Revision)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "article": article, "revision": revision})
+}
+
+// saveWithRevision 在同一事务中保存文章并记录修订版本，内容未变化时不产生新版本；失败时直接写入响应
+func saveWithRevision(c *gin.Context, articlesDB *database.ArticleData, original, article *database.Article, user *model.User, restoredFrom *int) (*database.Revision, bool) {
+	revisionsDB, err := database.UseRevisionData()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
+		return nil, false
+	}
+	defer revisionsDB.Close()
+
+	revision, err := revisionsDB.SaveArticle(original, article, user.Username, restoredFrom, revisionChanged(original, article))
+	if err != nil {
+		if articlesDB.IsDuplicateError(err) {
+			c.JSON(http.StatusBadRequest, gin.H{errorKey: duplicateError})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
+		return nil, false
+	}
+	if revision == nil {
+		return nil, true
+	}
+	revision.Content = ""
+
+	// 管理员修改他人文章需要留痕
+	if article.Author != user.Username {
+		detail := fmt.Sprintf("修订版本 %d", revision.Revision)
+		if restoredFrom != nil {
+			detail = fmt.Sprintf("恢复到版本 %d，生成修订版本 %d", *restoredFrom, revision.Revision)
+		}
+		audit.RecordFromContext(c, &user.UserID, audit.ActionArticleEdit, audit.TargetArticle, strconv.FormatInt(article.ID, 10), detail)
+	}
+	return revision, true
+}
+
+// loadEditableArticle 读取路由中的文章并确认当前用户是作者或管理员，失败时直接写入响应
+func loadEditableArticle(c *gin.Context, articlesDB *database.ArticleData) (*database.Article, *model.User, bool) {
+	user, err := utils.GetCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{errorKey: "无法获取用户信息"})
+		return nil, nil, false
+	}
+
+	articleID, ok := parseArticleID(c)
+	if !ok {
+		return nil, nil, false
+	}
+
+	article, err := articlesDB.GetArticleByID(articleID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{errorKey: "该文章不存在"})
+			return nil, nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
+		return nil, nil, false
+	}
+
+	if article.Author != user.Username && !user.IsAdmin {
+		c.JSON(http.StatusForbidden, gin.H{errorKey: "这篇文章不是你的哦"})
+		return nil, nil, false
+	}
+	return article, user, true
+}
+
+// findRevision 按版本号查询修订，失败时直接写入响应
+func findRevision(c *gin.Context, revisionsDB *database.RevisionData, articleID int64, raw string) (*database.Revision, bool) {
+	number, err := strconv.Atoi(raw)
+	if err != nil || number <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{errorKey: "输入格式错误，请重试"})
+		return nil, false
+	}
+
+	revision, err := revisionsDB.GetRevision(articleID, number)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{errorKey: "该修订版本不存在"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
+		return nil, false
+	}
+	return revision, true
+}
+
+// revisionChanged 修订只记录标题、摘要、正文和标签
+func revisionChanged(before, after *database.Article) bool {
+	return before.Title != after.Title ||
+		before.Excerpt != after.Excerpt ||
+		before.Content != after.Content ||
+		strings.Join(before.Tags, ",") != strings.Join(after.Tags, ",")
+}
+
+// parseTags 解析逗号分隔的标签，去掉空白和空项
+func parseTags(raw string) []string {
+	tags := []string{}
+	for _, tag := range strings.Split(raw, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// tagsMissing 返回在 a 中但不在 b 中的标签
+func tagsMissing(a, b []string) []string {
+	set := make(map[string]bool, len(b))
+	for _, tag := range b {
+		set[tag] = true
+	}
+	missing := []string{}
+	for _, tag := range a {
+		if !set[tag] {
+			missing = append(missing, tag)
+		}
+	}
+	return missing
+}
@@ -0,0 +1,204 @@
+package articles
+
+import (
+	"Backed/database"
+	"github.com/gin-gonic/gin"
+	"html"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	maxQueryLength = 100
+	snippetBefore  = 40  // 摘要片段中命中位置之前保留的字数
+	snippetLength  = 160 // 摘要片段的总字数
+)
+
+var (
+	markdownImage  = regexp.MustCompile(`!\[([^\]]*)\]\([^)]*\)`)
+	markdownLink   = regexp.MustCompile(`\[([^\]]*)\]\([^)]*\)`)
+	markdownSymbol = strings.NewReplacer("#", "", "*", "", "`", "", ">", "", "~", "", "|", " ")
+)
+
+// searchHit 搜索结果，高亮字段已经过 HTML 转义，只包含 <mark> 标签
+type searchHit struct {
+	*database.Article
+	Score          float64 `json:"score"`
+	TitleHighlight string  `json:"title_highlight"`
+	Snippet        string  `json:"snippet"`
+}
+
+func Search(c *gin.Context) {
+	query := strings.TrimSpace(c.Query("q"))
+	if query == "" {
+		c.JSON(http.StatusBadRequest, gin.H{errorKey: "请输入搜索关键词"})
+		return
+	}
+	if utf8.RuneCountInString(query) > maxQueryLength {
+		c.JSON(http.StatusBadRequest, gin.H{errorKey: "搜索关键词过长"})
+		return
+	}
+
+	filter := database.ArticleFilter{
+		Category: c.Query("category"),
+		Tag:      c.Query("tag"),
+		Author:   c.Query("author"),
+	}
+	if raw := c.Query("featured"); raw != "" {
+		featured, err := strconv.ParseBool(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{errorKey: "输入格式错误，请重试"})
+			return
+		}
+		filter.FeaturedOnly = featured
+	}
+
+	page, pageSize := parsePage(c)
+
+	articlesDB, err := database.UseArticleData()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
+		return
+	}
+	defer articlesDB.Close()
+
+	results, total, err := articlesDB.SearchArticles(query, filter, pageSize, (page-1)*pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
+		return
+	}
+
+	terms := database.SearchTerms(query)
+	hits := make([]searchHit, 0, len(results))
+	for _, result := range results {
+		hits = append(hits, searchHit{
+			Article:        result.Article,
+			Score:          result.Score,
+			TitleHighlight: highlight(result.Title, terms),
+			Snippet:        snippet(result.Excerpt, result.Content, terms),
+		})
+		// 搜索结果和列表一样不返回正文
+		result.Content = ""
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"results":   hits,
+		"total":     total,
+		"page":      page,
+		"page_size": pageSize,
+	})
+}
+
+// snippet 从正文中截取第一个命中关键词附近的文字，正文没有命中时使用摘要
+func snippet(excerpt, content string, terms []string) string {
+	text := plainText(content)
+	runes := []rune(text)
+	start, _ := findTerm(lowerRunes(runes), lowerTerms(terms), 0)
+	if start < 0 {
+		if excerpt != "" {
+			text = excerpt
+			runes = []rune(text)
+		}
+		start = 0
+	}
+
+	from := start - snippetBefore
+	if from < 0 {
+		from = 0
+	}
+	to := from + snippetLength
+	if to > len(runes) {
+		to = len(runes)
+	}
+
+	result := highlight(string(runes[from:to]), terms)
+	if from > 0 {
+		result = "…" + result
+	}
+	if to < len(runes) {
+		result += "…"
+	}
+	return result
+}
+
+// highlight 转义文本并用 <mark> 标出关键词，不区分大小写
+func highlight(text string, terms []string) string {
+	runes := []rune(text)
+	lower := lowerRunes(runes)
+	needles := lowerTerms(terms)
+
+	var b strings.Builder
+	pos := 0
+	for pos < len(runes) {
+		start, length := findTerm(lower, needles, pos)
+		if start < 0 {
+			break
+		}
+		b.WriteString(html.EscapeString(string(runes[pos:start])))
+		b.WriteString("<mark>")
+		b.WriteString(html.EscapeString(string(runes[start : start+length])))
+		b.WriteString("</mark>")
+		pos = start + length
+	}
+	b.WriteString(html.EscapeString(string(runes[pos:])))
+	return b.String()
+}
+
+// findTerm 从 from 开始查找最早出现的关键词，同一位置优先匹配更长的关键词
+func findTerm(text []rune, needles [][]rune, from int) (int, int) {
+	for i := from; i < len(text); i++ {
+		best := 0
+		for _, needle := range needles {
+			if len(needle) > best && hasPrefixAt(text, needle, i) {
+				best = len(needle)
+			}
+		}
+		if best > 0 {
+			return i, best
+		}
+	}
+	return -1, 0
+}
+
+func hasPrefixAt(text, needle []rune, at int) bool {
+	if at+len(needle) > len(text) {
+		return false
+	}
+	for j, r := range needle {
+		if text[at+j] != r {
+			return false
+		}
+	}
+	return true
+}
+
+// lowerRunes 逐字转小写，保持与原文的位置一一对应
+func lowerRunes(runes []rune) []rune {
+	lower := make([]rune, len(runes))
+	for i, r := range runes {
+		lower[i] = unicode.ToLower(r)
+	}
+	return lower
+}
+
+func lowerTerms(terms []string) [][]rune {
+	needles := make([][]rune, 0, len(terms))
+	for _, term := range terms {
+		if term != "" {
+			needles = append(needles, lowerRunes([]rune(term)))
+		}
+	}
+	return needles
+}
+
+// plainText 粗略去掉 Markdown 标记并合并空白，只用于生成摘要片段
+func plainText(source string) string {
+	text := markdownImage.ReplaceAllString(source, "$1")
+	text = markdownLink.ReplaceAllString(text, "$1")
+	text = markdownSymbol.Replace(text)
+	return strings.Join(strings.Fields(text), " ")
+}
@@ -0,0 +1,73 @@
+package articles
+
+import (
+	"strings"
+	"testing"
+)
+
+// stripMarks 去掉 <mark> 标签后检查是否还有未转义的 HTML
+func stripMarks(s string) string {
+	return strings.NewReplacer("<mark>", "", "</mark>", "").Replace(s)
+}
+
+func TestHighlight(t *testing.T) {
+	cases := []struct {
+		name  string
+		text  string
+		terms []string
+		want  string
+	}{
+		{"标题中的标签被转义", "<b>Go</b> 入门", []string{"go"}, "&lt;b&gt;<mark>Go</mark>&lt;/b&gt; 入门"},
+		{"关键词本身含有 HTML", "用 <script> 标签", []string{"<script>"}, "用 <mark>&lt;script&gt;</mark> 标签"},
+		{"命中标签中的字母", "<b>x</b>", []string{"b"}, "&lt;<mark>b</mark>&gt;x&lt;/<mark>b</mark>&gt;"},
+		{"引号和 & 被转义", `Tom & "Jerry"`, []string{"jerry"}, "Tom &amp; &#34;<mark>Jerry</mark>&#34;"},
+		{"不区分大小写", "GoLang and golang", []string{"GOLANG"}, "<mark>GoLang</mark> and <mark>golang</mark>"},
+		{"优先匹配更长的关键词", "database", []string{"data", "database"}, "<mark>database</mark>"},
+		{"中文关键词", "全文搜索与搜索引擎", []string{"搜索"}, "全文<mark>搜索</mark>与<mark>搜索</mark>引擎"},
+		{"没有命中", "<i>hi</i>", []string{"zz"}, "&lt;i&gt;hi&lt;/i&gt;"},
+		{"转义后的实体名不会被命中", "a & b", []string{"amp"}, "a &amp; b"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := highlight(c.text, c.terms)
+			if got != c.want {
+				t.Fatalf("highlight(%q) = %q，期望 %q", c.text, got, c.want)
+			}
+			if rest := stripMarks(got); strings.ContainsAny(rest, "<>\"") {
+				t.Fatalf("除 <mark> 外不应有未转义的字符: %q", got)
+			}
+		})
+	}
+}
+
+func TestSnippet(t *testing.T) {
+	long := strings.Repeat("前文", 50) + "关键词<img src=x onerror=alert(1)>" + strings.Repeat("后文", 100)
+	cases := []struct {
+		name           string
+		excerpt        string
+		content        string
+		terms          []string
+		contains       []string
+		prefix, suffix bool
+	}{
+		{"正文命中时截取附近文字", "摘要", long, []string{"关键词"}, []string{"<mark>关键词</mark>&lt;img"}, true, true},
+		{"正文未命中时使用摘要", "一段<b>摘要</b>", "正文内容", []string{"缺失"}, []string{"一段&lt;b&gt;摘要&lt;/b&gt;"}, false, false},
+		{"去掉 Markdown 标记", "", "# 标题\n\n**重点** 和 [链接](https://example.com)", []string{"重点"}, []string{"标题 <mark>重点</mark> 和 链接"}, false, false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := snippet(c.excerpt, c.content, c.terms)
+			for _, want := range c.contains {
+				if !strings.Contains(got, want) {
+					t.Fatalf("snippet = %q，缺少 %q", got, want)
+				}
+			}
+			if strings.HasPrefix(got, "…") != c.prefix || strings.HasSuffix(got, "…") != c.suffix {
+				t.Fatalf("省略号位置错误: %q", got)
+			}
+			if rest := stripMarks(got); strings.ContainsAny(rest, "<>\"") {
+				t.Fatalf("除 <mark> 外不应有未转义的字符: %q", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,206 @@
+package attachments
+
+import (
+	"Backed/database/dal"
+	"Backed/database/model"
+	"Backed/utils"
+	"Backed/utils/attachment"
+	"Backed/utils/relation"
+	"Backed/utils/retention"
+	"Backed/utils/storage"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"mime"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	errorKey      = "error"
+	internalError = "服务器内部错误，请稍后再试"
+	inputError    = "输入格式错误，请重试"
+	notFound      = "该附件不存在"
+)
+
+func Upload(c *gin.Context) {
+	user, err := utils.GetCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{errorKey: "无法获取用户信息"})
+		return
+	}
+
+	header, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{errorKey: "请选择要上传的文件"})
+		return
+	}
+	file, err := header.Open()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
+		return
+	}
+	defer file.Close()
+
+	// 指定会话对方时，附件随该会话的保留时长自动过期
+	var peerID int64
+	if raw := c.PostForm("peer_id"); raw != "" {
+		peerID, err = strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{errorKey: inputError})
+			return
+		}
+		var count int64
+		if err := dal.PostgreSQL.Model(&model.User{}).Where("user_id = ?", peerID).Count(&count).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
+			return
+		}
+		if count == 0 {
+			c.JSON(http.StatusNotFound, gin.H{errorKey: "用户不存在"})
+			return
+		}
+		// 对方可以访问附件，任一方屏蔽对方后不允许再发送
+		if peerID != user.UserID {
+			blocked, err := relation.HasBlockBetween(user.UserID, peerID)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
+				return
+			}
+			if blocked {
+				c.JSON(http.StatusForbidden, gin.H{errorKey: "无法向该用户发送附件"})
+				return
+			}
+		}
+	}
+
+	saved, err := attachment.Save(c.Request.Context(), user.UserID, c.PostForm("kind"), header.Filename, file)
+	if err != nil {
+		switch {
+		case errors.Is(err, attachment.ErrInvalidKind), errors.Is(err, attachment.ErrTypeNotAllowed):
+			c.JSON(http.StatusBadRequest, gin.H{errorKey: err.Error()})
+		case errors.Is(err, attachment.ErrTooLarge):
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{errorKey: err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
+		}
+		return
+	}
+	if peerID != 0 {
+		if err := retention.ApplyToAttachment(saved, peerID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
+			return
+		}
+	}
+
+	url, expires := storage.SignDownloadURL(saved.ID)
+	c.JSON(http.StatusOK, gin.H{"attachment": saved, "url": url, "expires_at": expires})
+}
+
+func GetURL(c *gin.Context) {
+	user, err := utils.GetCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{errorKey: "无法获取用户信息"})
+		return
+	}
+
+	att, ok := findAttachment(c)
+	if !ok {
+		return
+	}
+	isPeer := att.PeerID != nil && *att.PeerID == user.UserID
+	if att.UploaderID != user.UserID && !isPeer && !user.IsAdmin {
+		c.JSON(http.StatusForbidden, gin.H{errorKey: "你没有权限访问该附件"})
+		return
+	}
+
+	url, expires := storage.SignDownloadURL(att.ID)
+	c.JSON(http.StatusOK, gin.H{"url": url, "expires_at": expires})
+}
+
+func Download(c *gin.Context) {
+	att, ok := findAttachment(c)
+	if !ok {
+		return
+	}
+	if !storage.VerifyDownloadURL(att.ID, c.Query("expires"), c.Query("sig")) {
+		c.JSON(http.StatusForbidden, gin.H{errorKey: "下载链接无效或已过期"})
+		return
+	}
+	// 已过期但尚未被清理的附件
+	if att.ExpiresAt != nil && att.ExpiresAt.Before(time.Now()) {
+		c.JSON(http.StatusNotFound, gin.H{errorKey: notFound})
+		return
+	}
+
+	reader, blob, err := attachment.Open(c.Request.Context(), att)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) || errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{errorKey: notFound})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
+		return
+	}
+	defer reader.Close()
+
+	// 图片和语音直接展示，其他文件作为下载
+	disposition := "attachment"
+	if att.Kind == model.AttachmentImage || att.Kind == model.AttachmentVoice {
+		disposition = "inline"
+	}
+	headers := map[string]string{
+		"Content-Disposition":    mime.FormatMediaType(disposition, map[string]string{"filename": att.FileName}),
+		"Cache-Control":          "private, max-age=3600",
+		"X-Content-Type-Options": "nosniff",
+		"ETag":                   fmt.Sprintf("%q", blob.Hash),
+	}
+	c.DataFromReader(http.StatusOK, blob.Size, blob.ContentType, reader, headers)
+}
+
+func DeleteAttachment(c *gin.Context) {
+	user, err := utils.GetCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{errorKey: "无法获取用户信息"})
+		return
+	}
+
+	att, ok := findAttachment(c)
+	if !ok {
+		return
+	}
+	if att.UploaderID != user.UserID {
+		c.JSON(http.StatusForbidden, gin.H{errorKey: "这个附件不是你的哦"})
+		return
+	}
+
+	// 文件本身由后台任务在无引用后清理
+	if err := dal.PostgreSQL.Delete(att).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
+
+// findAttachment 根据路由参数查询附件，失败时直接写入响应
+func findAttachment(c *gin.Context) (*model.Attachment, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{errorKey: inputError})
+		return nil, false
+	}
+
+	var att model.Attachment
+	err = dal.PostgreSQL.Where("id = ?", id).First(&att).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{errorKey: notFound})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
+		return nil, false
+	}
+	return &att, true
+}
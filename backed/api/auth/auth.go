@@ -4,12 +4,14 @@ import (
 	"Backed/database/dal"
 	"Backed/database/model"
 	"Backed/utils"
+	"Backed/utils/audit"
 	"database/sql"
 	"errors"
 	"github.com/bwmarrin/snowflake"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 	"net/http"
+	"strconv"
 	"time"
 )
 
@@ -51,10 +53,18 @@ func Login(c *gin.Context) {
 		return
 	}
 	if !match {
+		audit.RecordFromContext(c, nil, audit.ActionLoginFailed, audit.TargetUser, strconv.FormatInt(user.UserID, 10), "密码错误")
 		c.JSON(http.StatusUnauthorized, gin.H{errorKey: invalidCreds})
 		return
 	}
 
+	// 封禁中的账号不允许登录
+	if user.IsBanActive(time.Now()) {
+		audit.RecordFromContext(c, nil, audit.ActionLoginFailed, audit.TargetUser, strconv.FormatInt(user.UserID, 10), "账号已封禁")
+		c.JSON(http.StatusForbidden, utils.BanResponse(&user))
+		return
+	}
+
 	// 生成令牌
 	token, err := utils.GenerateToken(username)
 	if err != nil {
@@ -62,6 +72,8 @@ func Login(c *gin.Context) {
 		return
 	}
 
+	audit.RecordFromContext(c, &user.UserID, audit.ActionLogin, audit.TargetUser, strconv.FormatInt(user.UserID, 10), "")
+
 	// 返回令牌
 	c.JSON(http.StatusOK, gin.H{"token": token})
 }
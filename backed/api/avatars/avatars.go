@@ -0,0 +1,161 @@
+package avatars
+
+import (
+	"Backed/database/dal"
+	"Backed/database/model"
+	"Backed/utils"
+	"Backed/utils/avatar"
+	"Backed/utils/storage"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"log"
+	"net/http"
+	"strconv"
+)
+
+const (
+	errorKey      = "error"
+	internalError = "服务器内部错误，请稍后再试"
+	inputError    = "输入格式错误，请重试"
+	notFound      = "该用户还没有设置头像"
+)
+
+func Upload(c *gin.Context) {
+	user, err := utils.GetCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{errorKey: "无法获取用户信息"})
+		return
+	}
+
+	header, err := c.FormFile("avatar")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{errorKey: "请选择要上传的图片"})
+		return
+	}
+	if header.Size > avatar.MaxUploadSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{errorKey: avatar.ErrTooLarge.Error()})
+		return
+	}
+	file, err := header.Open()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
+		return
+	}
+	defer file.Close()
+
+	processed, err := avatar.Process(file)
+	if err != nil {
+		switch {
+		case errors.Is(err, avatar.ErrTooLarge):
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{errorKey: err.Error()})
+		case errors.Is(err, avatar.ErrInvalidImage):
+			c.JSON(http.StatusBadRequest, gin.H{errorKey: err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
+		}
+		return
+	}
+
+	ctx := c.Request.Context()
+	if err := avatar.Store(ctx, avatar.OwnerUser, user.UserID, processed); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
+		return
+	}
+
+	oldVersion := user.AvatarVersion
+	err = dal.PostgreSQL.Model(&model.User{}).Where("user_id = ?", user.UserID).
+		Update("avatar_version", processed.Version).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
+		return
+	}
+
+	// 清理旧版本，失败不影响本次上传
+	if oldVersion != "" && oldVersion != processed.Version {
+		if err := avatar.Remove(ctx, avatar.OwnerUser, user.UserID, oldVersion); err != nil {
+			log.Printf("删除旧头像失败: %s", err)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "avatar": avatar.URL(avatar.OwnerUser, user.UserID, processed.Version)})
+}
+
+func DeleteAvatar(c *gin.Context) {
+	user, err := utils.GetCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{errorKey: "无法获取用户信息"})
+		return
+	}
+	if user.AvatarVersion == "" {
+		c.JSON(http.StatusOK, gin.H{"success": true})
+		return
+	}
+
+	err = dal.PostgreSQL.Model(&model.User{}).Where("user_id = ?", user.UserID).
+		Update("avatar_version", "").Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
+		return
+	}
+
+	if err := avatar.Remove(c.Request.Context(), avatar.OwnerUser, user.UserID, user.AvatarVersion); err != nil {
+		log.Printf("删除头像失败: %s", err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
+
+func GetUserAvatar(c *gin.Context) {
+	userID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{errorKey: inputError})
+		return
+	}
+
+	var user model.User
+	err = dal.PostgreSQL.Select("user_id", "avatar_version").Where("user_id = ?", userID).First(&user).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{errorKey: "用户不存在"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
+		return
+	}
+	if user.AvatarVersion == "" {
+		c.JSON(http.StatusNotFound, gin.H{errorKey: notFound})
+		return
+	}
+
+	size := avatar.DefaultSize
+	if raw := c.Query("size"); raw != "" {
+		requested, err := strconv.Atoi(raw)
+		if err != nil || requested <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{errorKey: inputError})
+			return
+		}
+		size = avatar.NearestSize(requested)
+	}
+
+	reader, err := storage.Default.Get(c.Request.Context(), avatar.Key(avatar.OwnerUser, user.UserID, user.AvatarVersion, size))
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{errorKey: notFound})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
+		return
+	}
+	defer reader.Close()
+
+	// 带有当前版本号的地址内容不会变化，可以长期缓存
+	cacheControl := "public, max-age=300"
+	if c.Query("v") == user.AvatarVersion {
+		cacheControl = "public, max-age=31536000, immutable"
+	}
+	c.DataFromReader(http.StatusOK, -1, "image/jpeg", reader, map[string]string{
+		"Cache-Control": cacheControl,
+		"ETag":          `"` + user.AvatarVersion + "_" + strconv.Itoa(size) + `"`,
+	})
+}
@@ -0,0 +1,112 @@
+package blocks
+
+import (
+	"Backed/database/dal"
+	"Backed/database/model"
+	"Backed/utils"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"net/http"
+	"time"
+)
+
+const (
+	errorKey      = "error"
+	internalError = "服务器内部错误，请稍后再试"
+	userNotFound  = "用户不存在"
+)
+
+type blockedUser struct {
+	UserID    int64     `json:"user_id,string"`
+	Username  string    `json:"username"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func GetList(c *gin.Context) {
+	user, err := utils.GetCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{errorKey: "无法获取用户信息"})
+		return
+	}
+
+	var list []blockedUser
+	err = dal.PostgreSQL.Table("user_blocks").
+		Select("users.user_id, users.username, user_blocks.created_at").
+		Joins("JOIN users ON users.user_id = user_blocks.blocked_id").
+		Where("user_blocks.blocker_id = ?", user.UserID).
+		Order("user_blocks.created_at DESC").
+		Scan(&list).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"blocks": list})
+}
+
+func AddBlock(c *gin.Context) {
+	user, err := utils.GetCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{errorKey: "无法获取用户信息"})
+		return
+	}
+
+	target, ok := findTarget(c)
+	if !ok {
+		return
+	}
+	if target.UserID == user.UserID {
+		c.JSON(http.StatusBadRequest, gin.H{errorKey: "不能拉黑自己"})
+		return
+	}
+
+	// 重复拉黑视为成功
+	block := model.UserBlock{BlockerID: user.UserID, BlockedID: target.UserID}
+	err = dal.PostgreSQL.Clauses(clause.OnConflict{DoNothing: true}).Create(&block).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
+
+func DeleteBlock(c *gin.Context) {
+	user, err := utils.GetCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{errorKey: "无法获取用户信息"})
+		return
+	}
+
+	target, ok := findTarget(c)
+	if !ok {
+		return
+	}
+
+	err = dal.PostgreSQL.
+		Where("blocker_id = ? AND blocked_id = ?", user.UserID, target.UserID).
+		Delete(&model.UserBlock{}).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
+
+// findTarget 根据路由参数查询目标用户，失败时直接写入响应
+func findTarget(c *gin.Context) (*model.User, bool) {
+	var target model.User
+	err := dal.PostgreSQL.Where("username = ?", c.Param("username")).First(&target).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{errorKey: userNotFound})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
+		return nil, false
+	}
+	return &target, true
+}
@@ -0,0 +1,166 @@
+package calls
+
+import (
+	"Backed/database/dal"
+	"Backed/database/model"
+	"Backed/utils"
+	"Backed/utils/call"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	errorKey      = "error"
+	internalError = "服务器内部错误，请稍后再试"
+	inputError    = "输入格式错误，请重试"
+
+	pageSize = 30
+)
+
+// callLog 从当前用户视角展示的通话记录
+type callLog struct {
+	ID         string     `json:"id"`
+	Direction  string     `json:"direction"` // outgoing 或 incoming
+	PeerID     int64      `json:"peer_id,string"`
+	PeerName   string     `json:"peer_name"`
+	CallerID   int64      `json:"caller_id,string"`
+	CalleeID   int64      `json:"callee_id,string"`
+	Media      string     `json:"media"`
+	Outcome    string     `json:"outcome"`
+	Missed     bool       `json:"missed"`
+	Unread     bool       `json:"unread"`
+	StartedAt  time.Time  `json:"started_at"`
+	AnsweredAt *time.Time `json:"answered_at"`
+	Duration   int        `json:"duration"`
+}
+
+func GetList(c *gin.Context) {
+	user, err := utils.GetCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{errorKey: "无法获取用户信息"})
+		return
+	}
+
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	query := dal.PostgreSQL.Model(&model.CallRecord{}).
+		Where("caller_id = ? OR callee_id = ?", user.UserID, user.UserID)
+	if media := c.Query("type"); media != "" {
+		if media != model.CallAudio && media != model.CallVideo {
+			c.JSON(http.StatusBadRequest, gin.H{errorKey: inputError})
+			return
+		}
+		query = query.Where("media = ?", media)
+	}
+	if c.Query("missed") == "true" {
+		query = query.Where("callee_id = ? AND outcome IN ?", user.UserID, []string{model.CallMissed, model.CallBusy})
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
+		return
+	}
+
+	var records []model.CallRecord
+	err = query.Order("started_at DESC").Limit(pageSize).Offset((page - 1) * pageSize).Find(&records).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
+		return
+	}
+
+	names, err := peerNames(user.UserID, records)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
+		return
+	}
+
+	logs := make([]callLog, 0, len(records))
+	for i := range records {
+		r := &records[i]
+		entry := callLog{
+			ID:         r.ID,
+			Direction:  "outgoing",
+			PeerID:     r.CalleeID,
+			CallerID:   r.CallerID,
+			CalleeID:   r.CalleeID,
+			Media:      r.Media,
+			Outcome:    r.Outcome,
+			Missed:     r.IsMissedFor(user.UserID),
+			StartedAt:  r.StartedAt,
+			AnsweredAt: r.AnsweredAt,
+			Duration:   r.Duration,
+		}
+		if r.CalleeID == user.UserID {
+			entry.Direction = "incoming"
+			entry.PeerID = r.CallerID
+		}
+		entry.Unread = entry.Missed && !r.MissedRead
+		entry.PeerName = names[entry.PeerID]
+		logs = append(logs, entry)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"calls": logs, "total": total, "page": page})
+}
+
+func GetUnread(c *gin.Context) {
+	user, err := utils.GetCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{errorKey: "无法获取用户信息"})
+		return
+	}
+
+	unread, err := call.UnreadMissedCount(user.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"unread": unread})
+}
+
+func MarkRead(c *gin.Context) {
+	user, err := utils.GetCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{errorKey: "无法获取用户信息"})
+		return
+	}
+
+	if err := call.MarkMissedRead(user.UserID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
+
+// peerNames 批量查询通话对方的用户名
+func peerNames(userID int64, records []model.CallRecord) (map[int64]string, error) {
+	ids := make([]int64, 0, len(records))
+	for _, r := range records {
+		if r.CallerID == userID {
+			ids = append(ids, r.CalleeID)
+		} else {
+			ids = append(ids, r.CallerID)
+		}
+	}
+
+	names := make(map[int64]string, len(ids))
+	if len(ids) == 0 {
+		return names, nil
+	}
+
+	var users []model.User
+	if err := dal.PostgreSQL.Select("user_id", "username").Where("user_id IN ?", ids).Find(&users).Error; err != nil {
+		return nil, err
+	}
+	for _, u := range users {
+		names[u.UserID] = u.Username
+	}
+	return names, nil
+}
@@ -0,0 +1,108 @@
+package conversations
+
+import (
+	"Backed/database/dal"
+	"Backed/database/model"
+	"Backed/utils"
+	"Backed/utils/relation"
+	"Backed/utils/retention"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"net/http"
+	"strconv"
+)
+
+const (
+	errorKey      = "error"
+	internalError = "服务器内部错误，请稍后再试"
+	inputError    = "输入格式错误，请重试"
+)
+
+func GetRetention(c *gin.Context) {
+	user, err := utils.GetCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{errorKey: "无法获取用户信息"})
+		return
+	}
+
+	peer, ok := findPeer(c)
+	if !ok {
+		return
+	}
+
+	seconds, err := retention.Get(user.UserID, peer.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"seconds":     seconds,
+		"max_seconds": retention.MaxSeconds(),
+		"allowed":     retention.Allowed,
+	})
+}
+
+func SetRetention(c *gin.Context) {
+	user, err := utils.GetCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{errorKey: "无法获取用户信息"})
+		return
+	}
+
+	peer, ok := findPeer(c)
+	if !ok {
+		return
+	}
+	seconds, err := strconv.Atoi(c.PostForm("seconds"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{errorKey: inputError})
+		return
+	}
+
+	// 任一方屏蔽对方后不允许修改，否则设置变化仍会通知到对方
+	if peer.UserID != user.UserID {
+		blocked, err := relation.HasBlockBetween(user.UserID, peer.UserID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
+			return
+		}
+		if blocked {
+			c.JSON(http.StatusForbidden, gin.H{errorKey: "无法修改与该用户的会话设置"})
+			return
+		}
+	}
+
+	// 会话双方都可以修改保留时长
+	if err := retention.Set(user.UserID, peer.UserID, seconds); err != nil {
+		if errors.Is(err, retention.ErrInvalidDuration) {
+			c.JSON(http.StatusBadRequest, gin.H{errorKey: err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
+
+// findPeer 根据路由中的用户ID查询会话对方，失败时直接写入响应
+func findPeer(c *gin.Context) (*model.User, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{errorKey: inputError})
+		return nil, false
+	}
+	var peer model.User
+	err = dal.PostgreSQL.Where("user_id = ?", id).First(&peer).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{errorKey: "用户不存在"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
+		return nil, false
+	}
+	return &peer, true
+}
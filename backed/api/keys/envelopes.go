@@ -0,0 +1,221 @@
+package keys
+
+import (
+	"Backed/database/dal"
+	"Backed/database/model"
+	"Backed/utils"
+	"Backed/utils/e2ee"
+	"Backed/utils/relation"
+	"encoding/json"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const maxAckIDs = 100
+
+// GetBundle 同一请求方十分钟内重复获取返回相同的密钥包，不会再领取新的一次性预密钥
+func GetBundle(c *gin.Context) {
+	user, err := utils.GetCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{errorKey: "无法获取用户信息"})
+		return
+	}
+
+	target, ok := findUser(c, c.Param("id"))
+	if !ok {
+		return
+	}
+	if target.UserID != user.UserID {
+		blocked, err := relation.HasBlockBetween(user.UserID, target.UserID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
+			return
+		}
+		if blocked {
+			c.JSON(http.StatusForbidden, gin.H{errorKey: "无法与该用户建立加密会话"})
+			return
+		}
+	}
+
+	bundles, err := e2ee.FetchBundle(user.UserID, target.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
+		return
+	}
+	if len(bundles) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{errorKey: "该用户尚未启用端到端加密"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"user_id": strconv.FormatInt(target.UserID, 10),
+		"devices": bundles,
+	})
+}
+
+func SendEnvelopes(c *gin.Context) {
+	user, err := utils.GetCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{errorKey: "无法获取用户信息"})
+		return
+	}
+
+	senderDevice, ok := parseDeviceID(c, c.PostForm("device_id"))
+	if !ok {
+		return
+	}
+	target, ok := findUser(c, c.PostForm("recipient_id"))
+	if !ok {
+		return
+	}
+	var envelopes []e2ee.OutgoingEnvelope
+	if err := json.Unmarshal([]byte(c.PostForm("envelopes")), &envelopes); err != nil || len(envelopes) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{errorKey: inputError})
+		return
+	}
+	// 编辑消息时带上原消息ID，新内容作为新的密文发送；回复、转发和提及只登记ID
+	var opts e2ee.SendOptions
+	if opts.ReplacesID, ok = parseOptionalID(c, "replaces_id"); !ok {
+		return
+	}
+	if opts.ReplyToID, ok = parseOptionalID(c, "reply_to_id"); !ok {
+		return
+	}
+	if opts.ForwardedFromID, ok = parseOptionalID(c, "forwarded_from_id"); !ok {
+		return
+	}
+	// 被提及的用户ID，以逗号分隔
+	if raw := c.PostForm("mentions"); raw != "" {
+		for _, part := range strings.Split(raw, ",") {
+			id, err := strconv.ParseInt(strings.TrimSpace(part), 10, 64)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{errorKey: inputError})
+				return
+			}
+			opts.Mentions = append(opts.Mentions, id)
+		}
+	}
+
+	if target.UserID != user.UserID {
+		blocked, err := relation.HasBlockBetween(user.UserID, target.UserID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
+			return
+		}
+		if blocked {
+			c.JSON(http.StatusForbidden, gin.H{errorKey: "无法向该用户发送消息"})
+			return
+		}
+	}
+
+	message, err := e2ee.Send(user.UserID, senderDevice, target.UserID, opts, envelopes)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": message})
+}
+
+func GetInbox(c *gin.Context) {
+	user, err := utils.GetCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{errorKey: "无法获取用户信息"})
+		return
+	}
+
+	deviceID, ok := parseDeviceID(c, c.Query("device_id"))
+	if !ok {
+		return
+	}
+	afterID, err := strconv.ParseInt(c.DefaultQuery("after", "0"), 10, 64)
+	if err != nil || afterID < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{errorKey: inputError})
+		return
+	}
+
+	envelopes, err := e2ee.Inbox(user.UserID, deviceID, afterID)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"envelopes": envelopes,
+		"more":      len(envelopes) == e2ee.MaxInboxBatch,
+	})
+}
+
+func AckEnvelopes(c *gin.Context) {
+	user, err := utils.GetCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{errorKey: "无法获取用户信息"})
+		return
+	}
+
+	deviceID, ok := parseDeviceID(c, c.PostForm("device_id"))
+	if !ok {
+		return
+	}
+
+	// 已收到的密文ID，以逗号分隔
+	var ids []int64
+	for _, part := range strings.Split(c.PostForm("ids"), ",") {
+		id, err := strconv.ParseInt(strings.TrimSpace(part), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{errorKey: inputError})
+			return
+		}
+		ids = append(ids, id)
+	}
+	if len(ids) > maxAckIDs {
+		c.JSON(http.StatusBadRequest, gin.H{errorKey: "一次最多确认100条密文"})
+		return
+	}
+
+	deleted, err := e2ee.Ack(user.UserID, deviceID, ids)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"deleted": deleted})
+}
+
+// parseOptionalID 解析可选的消息ID表单字段，未提交时返回 nil；失败时直接写入响应
+func parseOptionalID(c *gin.Context, field string) (*int64, bool) {
+	raw := c.PostForm(field)
+	if raw == "" {
+		return nil, true
+	}
+	id, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{errorKey: inputError})
+		return nil, false
+	}
+	return &id, true
+}
+
+// findUser 根据用户ID查询目标用户，失败时直接写入响应
+func findUser(c *gin.Context, raw string) (*model.User, bool) {
+	id, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{errorKey: inputError})
+		return nil, false
+	}
+	var target model.User
+	err = dal.PostgreSQL.Where("user_id = ?", id).First(&target).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{errorKey: "用户不存在"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
+		return nil, false
+	}
+	return &target, true
+}
@@ -0,0 +1,225 @@
+package keys
+
+import (
+	"Backed/utils"
+	"Backed/utils/e2ee"
+	"encoding/json"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	errorKey      = "error"
+	internalError = "服务器内部错误，请稍后再试"
+	inputError    = "输入格式错误，请重试"
+)
+
+// RegisterDevice 登记设备。identity_key 为 base64 编码的 32 字节 Ed25519 公钥，
+// 预密钥为 32 字节的 X25519 公钥，签名是身份密钥对预密钥公钥的 Ed25519 签名；
+// 不接受带 0x05 前缀的 Curve25519 身份密钥，客户端需单独保存 Ed25519 签名密钥
+func RegisterDevice(c *gin.Context) {
+	user, err := utils.GetCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{errorKey: "无法获取用户信息"})
+		return
+	}
+
+	deviceID, ok := parseDeviceID(c, c.PostForm("device_id"))
+	if !ok {
+		return
+	}
+	name := strings.TrimSpace(c.PostForm("name"))
+	if len([]rune(name)) > 64 {
+		c.JSON(http.StatusBadRequest, gin.H{errorKey: "设备名称过长"})
+		return
+	}
+
+	// 签名预密钥和一次性预密钥均以 JSON 字符串提交
+	var signed e2ee.SignedPreKey
+	if err := json.Unmarshal([]byte(c.PostForm("signed_pre_key")), &signed); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{errorKey: inputError})
+		return
+	}
+	preKeys, ok := parsePreKeys(c)
+	if !ok {
+		return
+	}
+
+	err = e2ee.RegisterDevice(user.UserID, deviceID, name, c.PostForm("identity_key"), signed, preKeys)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
+
+func UpdateSignedPreKey(c *gin.Context) {
+	user, err := utils.GetCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{errorKey: "无法获取用户信息"})
+		return
+	}
+
+	deviceID, ok := parseDeviceID(c, c.PostForm("device_id"))
+	if !ok {
+		return
+	}
+	var signed e2ee.SignedPreKey
+	if err := json.Unmarshal([]byte(c.PostForm("signed_pre_key")), &signed); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{errorKey: inputError})
+		return
+	}
+
+	if err := e2ee.UpdateSignedPreKey(user.UserID, deviceID, signed); err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
+
+func AddPreKeys(c *gin.Context) {
+	user, err := utils.GetCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{errorKey: "无法获取用户信息"})
+		return
+	}
+
+	deviceID, ok := parseDeviceID(c, c.PostForm("device_id"))
+	if !ok {
+		return
+	}
+	preKeys, ok := parsePreKeys(c)
+	if !ok {
+		return
+	}
+	if len(preKeys) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{errorKey: inputError})
+		return
+	}
+
+	if err := e2ee.AddPreKeys(user.UserID, deviceID, preKeys); err != nil {
+		writeError(c, err)
+		return
+	}
+
+	count, err := e2ee.CountPreKeys(user.UserID, deviceID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"count": count})
+}
+
+func GetPreKeyCount(c *gin.Context) {
+	user, err := utils.GetCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{errorKey: "无法获取用户信息"})
+		return
+	}
+
+	deviceID, ok := parseDeviceID(c, c.Query("device_id"))
+	if !ok {
+		return
+	}
+
+	count, err := e2ee.CountPreKeys(user.UserID, deviceID)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"count": count})
+}
+
+func GetDevices(c *gin.Context) {
+	user, err := utils.GetCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{errorKey: "无法获取用户信息"})
+		return
+	}
+
+	devices, err := e2ee.ListDevices(user.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"devices": devices})
+}
+
+func DeleteDevice(c *gin.Context) {
+	user, err := utils.GetCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{errorKey: "无法获取用户信息"})
+		return
+	}
+
+	deviceID, ok := parseDeviceID(c, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	if err := e2ee.DeleteDevice(user.UserID, deviceID); err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
+
+// parseDeviceID 设备编号由客户端生成，必须为正整数
+func parseDeviceID(c *gin.Context, raw string) (int, bool) {
+	deviceID, err := strconv.Atoi(raw)
+	if err != nil || deviceID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{errorKey: inputError})
+		return 0, false
+	}
+	return deviceID, true
+}
+
+func parsePreKeys(c *gin.Context) ([]e2ee.SignedPreKey, bool) {
+	var preKeys []e2ee.SignedPreKey
+	if raw := c.PostForm("one_time_pre_keys"); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &preKeys); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{errorKey: inputError})
+			return nil, false
+		}
+	}
+	return preKeys, true
+}
+
+// writeError 将 e2ee 包的错误映射为响应
+func writeError(c *gin.Context, err error) {
+	var mismatch *e2ee.MismatchError
+	switch {
+	case errors.As(err, &mismatch):
+		c.JSON(http.StatusConflict, gin.H{
+			errorKey:          err.Error(),
+			"missing_devices": mismatch.Missing,
+			"extra_devices":   mismatch.Extra,
+		})
+	case errors.Is(err, e2ee.ErrDeviceNotFound),
+		errors.Is(err, e2ee.ErrMessageNotFound):
+		c.JSON(http.StatusNotFound, gin.H{errorKey: err.Error()})
+	case errors.Is(err, e2ee.ErrMessageRecalled),
+		errors.Is(err, e2ee.ErrRecallExpired),
+		errors.Is(err, e2ee.ErrEditExpired):
+		c.JSON(http.StatusForbidden, gin.H{errorKey: err.Error()})
+	case errors.Is(err, e2ee.ErrEnvelopeTooLarge):
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{errorKey: err.Error()})
+	case errors.Is(err, e2ee.ErrInvalidKey),
+		errors.Is(err, e2ee.ErrInvalidSignature),
+		errors.Is(err, e2ee.ErrTooManyDevices),
+		errors.Is(err, e2ee.ErrTooManyPreKeys),
+		errors.Is(err, e2ee.ErrInvalidMention):
+		c.JSON(http.StatusBadRequest, gin.H{errorKey: err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
+	}
+}
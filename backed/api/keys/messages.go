@@ -0,0 +1,116 @@
+package keys
+
+import (
+	"Backed/utils"
+	"Backed/utils/e2ee"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RecallMessage 发送方在时间窗口内撤回消息，编辑版本一并撤回
+func RecallMessage(c *gin.Context) {
+	user, err := utils.GetCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{errorKey: "无法获取用户信息"})
+		return
+	}
+
+	messageID, ok := parseMessageID(c)
+	if !ok {
+		return
+	}
+
+	message, err := e2ee.Recall(user.UserID, messageID)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": message})
+}
+
+// GetMessageHistory 消息的各个版本，发送方和接收方都可查看
+func GetMessageHistory(c *gin.Context) {
+	user, err := utils.GetCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{errorKey: "无法获取用户信息"})
+		return
+	}
+
+	messageID, ok := parseMessageID(c)
+	if !ok {
+		return
+	}
+
+	versions, err := e2ee.History(user.UserID, messageID)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"versions": versions})
+}
+
+// GetRecalled 离线期间被撤回的消息，since 为上次同步时间(Unix 秒)
+func GetRecalled(c *gin.Context) {
+	user, err := utils.GetCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{errorKey: "无法获取用户信息"})
+		return
+	}
+
+	since, err := strconv.ParseInt(c.DefaultQuery("since", "0"), 10, 64)
+	if err != nil || since < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{errorKey: inputError})
+		return
+	}
+
+	messages, err := e2ee.Recalled(user.UserID, time.Unix(since, 0))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"messages": messages,
+		"more":     len(messages) == e2ee.MaxInboxBatch,
+	})
+}
+
+// GetMentions 提及当前用户的消息，before 为上一页最后一条消息的ID
+func GetMentions(c *gin.Context) {
+	user, err := utils.GetCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{errorKey: "无法获取用户信息"})
+		return
+	}
+
+	before, err := strconv.ParseInt(c.DefaultQuery("before", "0"), 10, 64)
+	if err != nil || before < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{errorKey: inputError})
+		return
+	}
+
+	messages, err := e2ee.Mentions(user.UserID, before)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"messages": messages,
+		"more":     len(messages) == e2ee.MaxInboxBatch,
+	})
+}
+
+// parseMessageID 解析路由中的消息ID，失败时直接写入响应
+func parseMessageID(c *gin.Context) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{errorKey: inputError})
+		return 0, false
+	}
+	return id, true
+}
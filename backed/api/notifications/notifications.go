@@ -0,0 +1,318 @@
+package notifications
+
+import (
+	"Backed/database/dal"
+	"Backed/database/model"
+	"Backed/utils"
+	"Backed/utils/push"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	errorKey      = "error"
+	internalError = "服务器内部错误，请稍后再试"
+	inputError    = "输入格式错误，请重试"
+
+	maxTokensPerUser = 20
+)
+
+func GetVAPIDKey(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"public_key": push.VAPIDPublicKey()})
+}
+
+func RegisterToken(c *gin.Context) {
+	user, err := utils.GetCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{errorKey: "无法获取用户信息"})
+		return
+	}
+
+	platform := c.PostForm("platform")
+	token := strings.TrimSpace(c.PostForm("token"))
+	if !push.Supported(platform) || token == "" || len(token) > 2048 {
+		c.JSON(http.StatusBadRequest, gin.H{errorKey: inputError})
+		return
+	}
+
+	// Web Push 的令牌为浏览器订阅地址，只接受已知推送服务；加密负载还需要订阅中的两个密钥
+	p256dh, auth := c.PostForm("p256dh"), c.PostForm("auth")
+	if platform == model.PushWeb {
+		if !push.ValidEndpoint(token) || p256dh == "" || auth == "" {
+			c.JSON(http.StatusBadRequest, gin.H{errorKey: inputError})
+			return
+		}
+	}
+
+	var count int64
+	err = dal.PostgreSQL.Model(&model.PushToken{}).
+		Where("user_id = ? AND NOT (platform = ? AND token = ?)", user.UserID, platform, token).
+		Count(&count).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
+		return
+	}
+	if count >= maxTokensPerUser {
+		c.JSON(http.StatusBadRequest, gin.H{errorKey: "推送设备数量已达上限"})
+		return
+	}
+
+	// 同一令牌换账号登录时归属新用户
+	record := model.PushToken{
+		UserID:   user.UserID,
+		Platform: platform,
+		Token:    token,
+		P256dh:   p256dh,
+		Auth:     auth,
+	}
+	err = dal.PostgreSQL.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "platform"}, {Name: "token"}},
+		DoUpdates: clause.AssignmentColumns([]string{"user_id", "p256dh", "auth", "updated_at"}),
+	}).Create(&record).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
+
+func UnregisterToken(c *gin.Context) {
+	user, err := utils.GetCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{errorKey: "无法获取用户信息"})
+		return
+	}
+
+	err = dal.PostgreSQL.
+		Where("user_id = ? AND platform = ? AND token = ?", user.UserID, c.PostForm("platform"), c.PostForm("token")).
+		Delete(&model.PushToken{}).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
+
+type settingView struct {
+	Enabled    bool   `json:"enabled"`
+	DNDEnabled bool   `json:"dnd_enabled"`
+	DNDStart   string `json:"dnd_start"`
+	DNDEnd     string `json:"dnd_end"`
+	Timezone   string `json:"timezone"`
+}
+
+func GetSettings(c *gin.Context) {
+	user, err := utils.GetCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{errorKey: "无法获取用户信息"})
+		return
+	}
+
+	setting, err := loadSetting(user.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"settings": toView(setting)})
+}
+
+func UpdateSettings(c *gin.Context) {
+	user, err := utils.GetCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{errorKey: "无法获取用户信息"})
+		return
+	}
+
+	setting, err := loadSetting(user.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
+		return
+	}
+
+	// 只修改提交了的字段
+	if raw, ok := c.GetPostForm("enabled"); ok {
+		if setting.Enabled, err = strconv.ParseBool(raw); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{errorKey: inputError})
+			return
+		}
+	}
+	if raw, ok := c.GetPostForm("dnd_enabled"); ok {
+		if setting.DNDEnabled, err = strconv.ParseBool(raw); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{errorKey: inputError})
+			return
+		}
+	}
+	if raw, ok := c.GetPostForm("dnd_start"); ok {
+		if setting.DNDStart, err = parseClock(raw); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{errorKey: "免打扰时间格式应为 HH:MM"})
+			return
+		}
+	}
+	if raw, ok := c.GetPostForm("dnd_end"); ok {
+		if setting.DNDEnd, err = parseClock(raw); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{errorKey: "免打扰时间格式应为 HH:MM"})
+			return
+		}
+	}
+	if raw, ok := c.GetPostForm("timezone"); ok {
+		if _, err := time.LoadLocation(raw); err != nil || raw == "" {
+			c.JSON(http.StatusBadRequest, gin.H{errorKey: "无效的时区"})
+			return
+		}
+		setting.Timezone = raw
+	}
+
+	if err := dal.PostgreSQL.Save(setting).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"settings": toView(setting)})
+}
+
+func GetMutes(c *gin.Context) {
+	user, err := utils.GetCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{errorKey: "无法获取用户信息"})
+		return
+	}
+
+	var mutes []model.PushMute
+	err = dal.PostgreSQL.
+		Where("user_id = ? AND (until IS NULL OR until > ?)", user.UserID, time.Now()).
+		Order("created_at DESC").
+		Find(&mutes).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"mutes": mutes})
+}
+
+func Mute(c *gin.Context) {
+	user, err := utils.GetCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{errorKey: "无法获取用户信息"})
+		return
+	}
+
+	peerID, ok := findPeer(c)
+	if !ok {
+		return
+	}
+
+	// duration 为静音秒数，不填或为 0 表示一直静音
+	mute := model.PushMute{UserID: user.UserID, PeerID: peerID}
+	if raw := c.PostForm("duration"); raw != "" {
+		seconds, err := strconv.Atoi(raw)
+		if err != nil || seconds < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{errorKey: inputError})
+			return
+		}
+		if seconds > 0 {
+			until := time.Now().Add(time.Duration(seconds) * time.Second)
+			mute.Until = &until
+		}
+	}
+
+	err = dal.PostgreSQL.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "peer_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"until"}),
+	}).Create(&mute).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"mute": mute})
+}
+
+func Unmute(c *gin.Context) {
+	user, err := utils.GetCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{errorKey: "无法获取用户信息"})
+		return
+	}
+
+	peerID, ok := findPeer(c)
+	if !ok {
+		return
+	}
+
+	err = dal.PostgreSQL.Where("user_id = ? AND peer_id = ?", user.UserID, peerID).Delete(&model.PushMute{}).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
+
+// loadSetting 读取推送设置，没有记录时返回默认值
+func loadSetting(userID int64) (*model.PushSetting, error) {
+	setting := model.PushSetting{
+		UserID:   userID,
+		Enabled:  true,
+		DNDStart: 22 * 60,
+		DNDEnd:   8 * 60,
+		Timezone: "Asia/Shanghai",
+	}
+	err := dal.PostgreSQL.Where("user_id = ?", userID).First(&setting).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	return &setting, nil
+}
+
+func toView(setting *model.PushSetting) settingView {
+	return settingView{
+		Enabled:    setting.Enabled,
+		DNDEnabled: setting.DNDEnabled,
+		DNDStart:   formatClock(setting.DNDStart),
+		DNDEnd:     formatClock(setting.DNDEnd),
+		Timezone:   setting.Timezone,
+	}
+}
+
+// parseClock 将 HH:MM 转为当天的分钟数
+func parseClock(raw string) (int, error) {
+	t, err := time.Parse("15:04", raw)
+	if err != nil {
+		return 0, err
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+func formatClock(minute int) string {
+	return fmt.Sprintf("%02d:%02d", minute/60, minute%60)
+}
+
+// findPeer 根据路由参数确认会话对方存在，失败时直接写入响应
+func findPeer(c *gin.Context) (int64, bool) {
+	peerID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{errorKey: inputError})
+		return 0, false
+	}
+	var count int64
+	if err := dal.PostgreSQL.Model(&model.User{}).Where("user_id = ?", peerID).Count(&count).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
+		return 0, false
+	}
+	if count == 0 {
+		c.JSON(http.StatusNotFound, gin.H{errorKey: "用户不存在"})
+		return 0, false
+	}
+	return peerID, true
+}
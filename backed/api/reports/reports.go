@@ -0,0 +1,83 @@
+package reports
+
+import (
+	"Backed/database/dal"
+	"Backed/database/model"
+	"Backed/utils"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	errorKey      = "error"
+	internalError = "服务器内部错误，请稍后再试"
+	inputError    = "输入格式错误，请重试"
+
+	maxReasonLength = 1000
+	maxMessageIDs   = 100
+)
+
+func AddReport(c *gin.Context) {
+	user, err := utils.GetCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{errorKey: "无法获取用户信息"})
+		return
+	}
+
+	reason := strings.TrimSpace(c.PostForm("reason"))
+	if reason == "" || utf8.RuneCountInString(reason) > maxReasonLength {
+		c.JSON(http.StatusBadRequest, gin.H{errorKey: "请填写举报理由(不超过1000字)"})
+		return
+	}
+
+	// 被举报的消息ID，以逗号分隔
+	var messageIDs []int64
+	if raw := c.PostForm("message_ids"); raw != "" {
+		for _, part := range strings.Split(raw, ",") {
+			id, err := strconv.ParseInt(strings.TrimSpace(part), 10, 64)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{errorKey: inputError})
+				return
+			}
+			messageIDs = append(messageIDs, id)
+		}
+	}
+	if len(messageIDs) > maxMessageIDs {
+		c.JSON(http.StatusBadRequest, gin.H{errorKey: "一次最多举报100条消息"})
+		return
+	}
+
+	var target model.User
+	err = dal.PostgreSQL.Where("username = ?", c.PostForm("target")).First(&target).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{errorKey: "用户不存在"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
+		return
+	}
+	if target.UserID == user.UserID {
+		c.JSON(http.StatusBadRequest, gin.H{errorKey: "不能举报自己"})
+		return
+	}
+
+	report := model.Report{
+		ReporterID: user.UserID,
+		TargetID:   target.UserID,
+		MessageIDs: messageIDs,
+		Reason:     reason,
+		Status:     model.ReportPending,
+	}
+	if err := dal.PostgreSQL.Create(&report).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{errorKey: internalError})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "id": report.ID})
+}
@@ -21,9 +21,10 @@ type DBConfig struct {
 }
 
 type AppConfig struct {
-	Name      string `yaml:"name"`
-	AppHost   string `yaml:"appHost"`
-	FrontHost string `yaml:"frontHost"`
+	Name           string   `yaml:"name"`
+	AppHost        string   `yaml:"appHost"`
+	FrontHost      string   `yaml:"frontHost"`
+	TrustedProxies []string `yaml:"trustedProxies"`
 }
 
 type SMTPConfig struct {
@@ -33,10 +34,56 @@ type SMTPConfig struct {
 	SMTPPassword string `yaml:"smtpPassword"`
 }
 
+type AccountConfig struct {
+	DeletionGraceDays int    `yaml:"deletionGraceDays"`
+	ExportDir         string `yaml:"exportDir"`
+	ExportTTLHours    int    `yaml:"exportTTLHours"`
+}
+
+type StorageConfig struct {
+	Backend       string `yaml:"backend"`
+	LocalDir      string `yaml:"localDir"`
+	SigningKey    string `yaml:"signingKey"`
+	URLTTLMinutes int    `yaml:"urlTTLMinutes"`
+	ImageMaxMB    int    `yaml:"imageMaxMB"`
+	FileMaxMB     int    `yaml:"fileMaxMB"`
+	VoiceMaxMB    int    `yaml:"voiceMaxMB"`
+
+	S3Endpoint  string `yaml:"s3Endpoint"`
+	S3Region    string `yaml:"s3Region"`
+	S3Bucket    string `yaml:"s3Bucket"`
+	S3AccessKey string `yaml:"s3AccessKey"`
+	S3SecretKey string `yaml:"s3SecretKey"`
+}
+
+type RetentionConfig struct {
+	MaxDays              int `yaml:"maxDays"`
+	PurgeIntervalMinutes int `yaml:"purgeIntervalMinutes"`
+}
+
+type MessageConfig struct {
+	RecallWindowMinutes int `yaml:"recallWindowMinutes"`
+	EditWindowMinutes   int `yaml:"editWindowMinutes"`
+}
+
+type PushConfig struct {
+	VAPIDPublicKey  string `yaml:"vapidPublicKey"`
+	VAPIDPrivateKey string `yaml:"vapidPrivateKey"`
+	VAPIDSubject    string `yaml:"vapidSubject"`
+	FCMEndpoint     string `yaml:"fcmEndpoint"`
+	APNsEndpoint    string `yaml:"apnsEndpoint"`
+	CollapseSeconds int    `yaml:"collapseSeconds"`
+}
+
 type Config struct {
-	Database DBConfig   `yaml:"database"`
-	App      AppConfig  `yaml:"app"`
-	SMTP     SMTPConfig `yaml:"smtp"`
+	Database  DBConfig        `yaml:"database"`
+	App       AppConfig       `yaml:"app"`
+	SMTP      SMTPConfig      `yaml:"smtp"`
+	Account   AccountConfig   `yaml:"account"`
+	Storage   StorageConfig   `yaml:"storage"`
+	Retention RetentionConfig `yaml:"retention"`
+	Message   MessageConfig   `yaml:"message"`
+	Push      PushConfig      `yaml:"push"`
 }
 
 func Load(path string) (*Config, error) {
@@ -2,12 +2,30 @@ package core
 
 import (
 	"Backed/api"
+	"Backed/api/account"
+	"Backed/api/admin"
 	"Backed/api/articles"
+	"Backed/api/attachments"
 	"Backed/api/auth"
+	"Backed/api/avatars"
+	"Backed/api/blocks"
+	"Backed/api/calls"
+	"Backed/api/conversations"
+	"Backed/api/keys"
+	"Backed/api/notifications"
+	"Backed/api/reports"
 	"Backed/config"
+	"Backed/database"
 	"Backed/database/dal"
 	"Backed/utils"
 	"Backed/utils/accout"
+	"Backed/utils/attachment"
+	"Backed/utils/call"
+	"Backed/utils/e2ee"
+	"Backed/utils/push"
+	"Backed/utils/realtime"
+	"Backed/utils/retention"
+	"Backed/utils/storage"
 	"fmt"
 	"github.com/gin-gonic/gin"
 	"log"
@@ -32,10 +50,49 @@ func StartServer() {
 		log.Fatal(err)
 	}
 
+	// 加载附件存储
+	if err := storage.Init(cfg.Storage); err != nil {
+		log.Fatalf("无法使用附件存储: %s", err)
+		return
+	}
+	attachment.Init(cfg.Storage)
+	go attachment.CleanupOrphanBlobsTask()
+
+	// 加载实时通道与通话信令
+	realtime.Init(cfg.App)
+	realtime.AllowTopic(articles.TopicPrefix, nil) // 文章的公开计数，任何已登录连接都可订阅
+	call.Init()
+
+	// 加载离线推送
+	if err := push.Init(cfg.Push); err != nil {
+		log.Fatalf("无法初始化推送: %s", err)
+		return
+	}
+
 	// 加载清理未激活账号程序
 	go accout.CleanupNotActiveUserTask()
 
+	// 加载数据导出与账号注销程序
+	accout.InitAccountConfig(cfg.Account)
+	go accout.DataExportTask()
+	go accout.AccountDeletionTask()
+
+	// 加载消息撤回与编辑时限
+	e2ee.Init(cfg.Message)
+
+	// 加载过期消息清理程序
+	retention.Init(cfg.Retention)
+	go retention.PurgeExpiredTask()
+
+	// 加载浏览记录清理程序
+	go database.CleanupViewRecordsTask()
+
 	router := gin.Default()
+	// 浏览去重和审计日志依赖客户端IP，未配置时不信任任何代理转发的地址
+	if err := router.SetTrustedProxies(cfg.App.TrustedProxies); err != nil {
+		log.Fatalf("无效的可信代理配置: %s", err)
+		return
+	}
 	initRoutes(router)
 	if err := router.Run(":8080"); err != nil {
 		fmt.Printf("启动服务器失败: %v\n", err)
@@ -48,10 +105,106 @@ func initRoutes(router *gin.Engine) {
 	router.POST("/register", auth.Register)
 	router.GET("/verify", auth.VerifyAuth)
 	router.GET("/me", utils.AuthMiddleware(), api.Me)
+	router.POST("/ws/ticket", utils.AuthMiddleware(), realtime.IssueTicket)
+	router.GET("/ws", realtime.ServeWebSocket)
+	router.GET("/sse", realtime.ServeSSE)
+	router.POST("/sse/send", utils.AuthMiddleware(), realtime.HandleSend)
+	router.GET("/calls", utils.AuthMiddleware(), calls.GetList)
+	router.GET("/calls/unread", utils.AuthMiddleware(), calls.GetUnread)
+	router.POST("/calls/read", utils.AuthMiddleware(), calls.MarkRead)
 
 	articleGroup := router.Group("/article")
 	articleGroup.GET("/list", articles.GetList)
+	articleGroup.GET("/search", articles.Search)
 	articleGroup.POST("/add", utils.AuthMiddleware(), articles.AddArticle)
 	articleGroup.POST("/delete/:id", utils.AuthMiddleware(), articles.DeleteArticle)
-	articleGroup.GET("/get/:id", articles.GetArticle)
+	articleGroup.GET("/get/:id", utils.OptionalAuthMiddleware(), articles.GetArticle)
+	articleGroup.PUT("/:id", utils.AuthMiddleware(), articles.UpdateArticle)
+	articleGroup.GET("/revision/list/:id", utils.AuthMiddleware(), articles.GetRevisions)
+	articleGroup.GET("/revision/get/:id", utils.AuthMiddleware(), articles.GetRevision)
+	articleGroup.GET("/revision/diff/:id", utils.AuthMiddleware(), articles.DiffRevisions)
+	articleGroup.POST("/revision/restore/:id", utils.AuthMiddleware(), articles.RestoreRevision)
+	articleGroup.GET("/reaction/list/:id", utils.OptionalAuthMiddleware(), articles.GetReactions)
+	articleGroup.GET("/reaction/users/:id", articles.GetReactors)
+	articleGroup.POST("/reaction/add/:id", utils.AuthMiddleware(), articles.AddReaction)
+	articleGroup.POST("/reaction/delete/:id", utils.AuthMiddleware(), articles.DeleteReaction)
+	articleGroup.POST("/like/:id", utils.AuthMiddleware(), articles.LikeArticle)
+	articleGroup.POST("/unlike/:id", utils.AuthMiddleware(), articles.UnlikeArticle)
+	articleGroup.GET("/bookmark/list", utils.AuthMiddleware(), articles.GetBookmarks)
+	articleGroup.POST("/bookmark/add/:id", utils.AuthMiddleware(), articles.AddBookmark)
+	articleGroup.POST("/bookmark/delete/:id", utils.AuthMiddleware(), articles.DeleteBookmark)
+	articleGroup.GET("/comment/list/:id", articles.GetComments)
+	articleGroup.GET("/comment/replies/:id", articles.GetReplies)
+	articleGroup.POST("/comment/add/:id", utils.AuthMiddleware(), articles.AddComment)
+	articleGroup.POST("/comment/edit/:id", utils.AuthMiddleware(), articles.EditComment)
+	articleGroup.POST("/comment/delete/:id", utils.AuthMiddleware(), articles.DeleteComment)
+
+	blockGroup := router.Group("/block", utils.AuthMiddleware())
+	blockGroup.GET("/list", blocks.GetList)
+	blockGroup.POST("/add/:username", blocks.AddBlock)
+	blockGroup.POST("/delete/:username", blocks.DeleteBlock)
+
+	keyGroup := router.Group("/keys", utils.AuthMiddleware())
+	keyGroup.GET("/devices", keys.GetDevices)
+	keyGroup.POST("/device/register", keys.RegisterDevice)
+	keyGroup.POST("/device/delete/:id", keys.DeleteDevice)
+	keyGroup.POST("/signed/update", keys.UpdateSignedPreKey)
+	keyGroup.POST("/prekeys/add", keys.AddPreKeys)
+	keyGroup.GET("/prekeys/count", keys.GetPreKeyCount)
+	keyGroup.GET("/bundle/:id", keys.GetBundle)
+
+	e2eeGroup := router.Group("/e2ee", utils.AuthMiddleware())
+	e2eeGroup.POST("/send", keys.SendEnvelopes)
+	e2eeGroup.GET("/inbox", keys.GetInbox)
+	e2eeGroup.POST("/ack", keys.AckEnvelopes)
+	e2eeGroup.POST("/message/recall/:id", keys.RecallMessage)
+	e2eeGroup.GET("/message/history/:id", keys.GetMessageHistory)
+	e2eeGroup.GET("/message/recalled", keys.GetRecalled)
+	e2eeGroup.GET("/message/mentions", keys.GetMentions)
+
+	conversationGroup := router.Group("/conversation", utils.AuthMiddleware())
+	conversationGroup.GET("/retention/:id", conversations.GetRetention)
+	conversationGroup.POST("/retention/:id", conversations.SetRetention)
+
+	pushGroup := router.Group("/push", utils.AuthMiddleware())
+	pushGroup.GET("/vapid", notifications.GetVAPIDKey)
+	pushGroup.POST("/register", notifications.RegisterToken)
+	pushGroup.POST("/unregister", notifications.UnregisterToken)
+	pushGroup.GET("/settings", notifications.GetSettings)
+	pushGroup.POST("/settings", notifications.UpdateSettings)
+	pushGroup.GET("/mute/list", notifications.GetMutes)
+	pushGroup.POST("/mute/:id", notifications.Mute)
+	pushGroup.POST("/unmute/:id", notifications.Unmute)
+
+	router.POST("/report/add", utils.AuthMiddleware(), reports.AddReport)
+
+	avatarGroup := router.Group("/avatar")
+	avatarGroup.POST("/upload", utils.AuthMiddleware(), avatars.Upload)
+	avatarGroup.POST("/delete", utils.AuthMiddleware(), avatars.DeleteAvatar)
+	avatarGroup.GET("/user/:id", avatars.GetUserAvatar)
+
+	attachmentGroup := router.Group("/attachment")
+	attachmentGroup.POST("/upload", utils.AuthMiddleware(), attachments.Upload)
+	attachmentGroup.GET("/url/:id", utils.AuthMiddleware(), attachments.GetURL)
+	attachmentGroup.GET("/download/:id", attachments.Download)
+	attachmentGroup.POST("/delete/:id", utils.AuthMiddleware(), attachments.DeleteAttachment)
+
+	accountGroup := router.Group("/account", utils.AuthMiddleware())
+	accountGroup.POST("/export/add", account.AddExport)
+	accountGroup.GET("/export/list", account.GetExportList)
+	accountGroup.GET("/export/download/:id", account.DownloadExport)
+	accountGroup.GET("/delete", account.GetDeletion)
+	accountGroup.POST("/delete/add", account.AddDeletion)
+	accountGroup.POST("/delete/cancel", account.CancelDeletion)
+
+	adminGroup := router.Group("/admin", utils.AuthMiddleware(), utils.AdminMiddleware())
+	adminGroup.GET("/user/list", admin.ListUsers)
+	adminGroup.POST("/user/ban/:id", admin.BanUser)
+	adminGroup.POST("/user/unban/:id", admin.UnbanUser)
+	adminGroup.POST("/user/logout/:id", admin.ForceLogout)
+	adminGroup.GET("/report/list", admin.ListReports)
+	adminGroup.POST("/report/handle/:id", admin.HandleReport)
+	adminGroup.POST("/article/delete/:id", admin.DeleteArticle)
+	adminGroup.GET("/audit/list", admin.ListAuditLogs)
+	adminGroup.GET("/audit/verify", admin.VerifyAuditLogs)
 }
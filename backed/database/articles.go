@@ -5,7 +5,11 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"log"
+	"sort"
+	"strings"
 	"time"
+	"unicode/utf8"
 )
 
 type Article struct {
@@ -21,11 +25,23 @@ type Article struct {
 	Category string    `json:"category"`
 	Tags     []string  `json:"tags"`
 	Featured bool      `json:"featured"`
-	Content  string    `json:"content"`
+	// 正文只在查询单篇文章时返回，列表接口不加载
+	Content     string `json:"content,omitempty"`
+	ContentHTML string `json:"content_html,omitempty"`
 }
 
+// articleListColumns 列表类查询使用的列，不包含正文
+var articleListColumns = []string{
+	"id", "title", "excerpt", "author", "date", "read_time",
+	"likes", "comments", "views", "category", "tags", "featured",
+}
+
+var articleListSelect = strings.Join(articleListColumns, ", ")
+
 type ArticleData struct {
 	db *utils.Database
+	// fullText 全文索引是否可用，不可用时搜索退化为 LIKE 匹配
+	fullText bool
 }
 
 func UseArticleData() (*ArticleData, error) {
@@ -47,6 +63,8 @@ func UseArticleData() (*ArticleData, error) {
 		{Name: "category", Type: "VARCHAR(100)"},
 		{Name: "tags", Type: "JSON"},
 		{Name: "featured", Type: "BOOLEAN", Default: "false"},
+		{Name: "content", Type: "MEDIUMTEXT"},
+		{Name: "content_html", Type: "MEDIUMTEXT"},
 	}
 
 	err = data.CreateTable("articles", articleTableColumns)
@@ -54,11 +72,62 @@ func UseArticleData() (*ArticleData, error) {
 		return nil, fmt.Errorf("无法创建文章数据表: %v", err)
 	}
 
+	// 旧版本创建的表没有正文列
+	for _, col := range articleTableColumns[len(articleTableColumns)-2:] {
+		if err := ensureColumn(data, "articles", col); err != nil {
+			return nil, fmt.Errorf("无法添加文章正文列: %v", err)
+		}
+	}
+
+	// 列表筛选与排序使用的索引
+	listIndexes := [][2]string{
+		{"idx_articles_date", "date, id"},
+		{"idx_articles_views", "views, id"},
+		{"idx_articles_likes", "likes, id"},
+		{"idx_articles_category", "category"},
+		{"idx_articles_author", "author"},
+	}
+	for _, index := range listIndexes {
+		if err := createIndex(data, "articles", index[0], "", index[1]); err != nil {
+			return nil, err
+		}
+	}
+
+	fullText := true
 	if err := createFullTextIndex(data); err != nil {
-		fmt.Errorf("无法创建全文索引: %v", err)
+		log.Printf("无法创建全文索引: %v", err)
+		fullText = false
+	}
+
+	return &ArticleData{db: data, fullText: fullText}, nil
+}
+
+// ensureColumn 列不存在时追加到表末尾
+func ensureColumn(db *utils.Database, table string, col utils.ColumnDefinition) error {
+	checkQuery := `
+        SELECT COUNT(*) FROM information_schema.columns
+        WHERE table_schema = DATABASE()
+        AND table_name = ?
+        AND column_name = ?
+    `
+
+	var count int
+	if err := db.QueryRow(checkQuery, table, col.Name).Scan(&count); err != nil {
+		return fmt.Errorf("检查列失败: %w", err)
+	}
+	if count > 0 {
+		return nil
 	}
 
-	return &ArticleData{db: data}, nil
+	def := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, col.Name, col.Type)
+	if !col.Nullable {
+		def += " NOT NULL"
+	}
+	if col.Default != "" {
+		def += " DEFAULT " + col.Default
+	}
+	_, err := db.Exec(def)
+	return err
 }
 
 // createFullTextIndex 创建完整索引
@@ -109,7 +178,8 @@ func (a *ArticleData) GetArticleByID(id int64) (*Article, error) {
 		"id": id,
 	}
 
-	rows, err := a.db.Select("articles", nil, where)
+	columns := append(append([]string{}, articleListColumns...), "content", "content_html")
+	rows, err := a.db.Select("articles", columns, where)
 	if err != nil {
 		return nil, fmt.Errorf("查询失败: %w", err)
 	}
@@ -135,6 +205,8 @@ func (a *ArticleData) GetArticleByID(id int64) (*Article, error) {
 		&article.Category,
 		&tagsJSON,
 		&article.Featured,
+		&article.Content,
+		&article.ContentHTML,
 	)
 
 	if err != nil {
@@ -165,17 +237,19 @@ func (a *ArticleData) InsertArticle(article *Article) (*Article, error) {
 	}
 
 	data := map[string]interface{}{
-		"title":     article.Title,
-		"excerpt":   article.Excerpt,
-		"author":    article.Author,
-		"date":      article.Date,
-		"read_time": article.ReadTime,
-		"likes":     article.Likes,
-		"comments":  article.Comments,
-		"views":     article.Views,
-		"category":  article.Category,
-		"tags":      string(tagsJSON),
-		"featured":  article.Featured,
+		"title":        article.Title,
+		"excerpt":      article.Excerpt,
+		"author":       article.Author,
+		"date":         article.Date,
+		"read_time":    article.ReadTime,
+		"likes":        article.Likes,
+		"comments":     article.Comments,
+		"views":        article.Views,
+		"category":     article.Category,
+		"tags":         string(tagsJSON),
+		"featured":     article.Featured,
+		"content":      article.Content,
+		"content_html": article.ContentHTML,
 	}
 
 	var id int64
@@ -199,18 +273,18 @@ func (a *ArticleData) UpdateArticle(article *Article) error {
 		return fmt.Errorf("序列化标签失败: %w", err)
 	}
 
+	// 点赞、评论、浏览数由原子操作维护，这里不覆盖，避免编辑时丢失并发的计数
 	data := map[string]interface{}{
-		"title":     article.Title,
-		"excerpt":   article.Excerpt,
-		"author":    article.Author,
-		"date":      article.Date,
-		"read_time": article.ReadTime,
-		"likes":     article.Likes,
-		"comments":  article.Comments,
-		"views":     article.Views,
-		"category":  article.Category,
-		"tags":      string(tagsJSON),
-		"featured":  article.Featured,
+		"title":        article.Title,
+		"excerpt":      article.Excerpt,
+		"author":       article.Author,
+		"date":         article.Date,
+		"read_time":    article.ReadTime,
+		"category":     article.Category,
+		"tags":         string(tagsJSON),
+		"featured":     article.Featured,
+		"content":      article.Content,
+		"content_html": article.ContentHTML,
 	}
 
 	where := map[string]interface{}{
@@ -243,6 +317,33 @@ func (a *ArticleData) DeleteArticle(id int64) (bool, error) {
 	return rowsAffected > 0, nil
 }
 
+func (a *ArticleData) ListArticlesByAuthor(author string) ([]*Article, error) {
+	where := map[string]interface{}{
+		"author": author,
+	}
+
+	rows, err := a.db.Select("articles", articleListColumns, where)
+	if err != nil {
+		return nil, fmt.Errorf("获取作者文章失败: %w", err)
+	}
+	defer rows.Close()
+
+	return a.scanArticles(rows)
+}
+
+func (a *ArticleData) DeleteArticlesByAuthor(author string) (int64, error) {
+	where := map[string]interface{}{
+		"author": author,
+	}
+
+	result, err := a.db.Delete("articles", where)
+	if err != nil {
+		return 0, fmt.Errorf("删除作者文章失败: %w", err)
+	}
+
+	return result.RowsAffected()
+}
+
 func (a *ArticleData) IncrementViews(id int64) error {
 	// 确保原子操作
 	query := "UPDATE articles SET views = views + 1 WHERE id = ?"
@@ -250,32 +351,106 @@ func (a *ArticleData) IncrementViews(id int64) error {
 	return err
 }
 
-func (a *ArticleData) ListArticles(limit, offset int, category, tag string, featuredOnly bool) ([]*Article, error) {
-	// 构建查询查询
-	where := make(map[string]interface{})
+// 文章列表的排序方式
+const (
+	SortNewest     = "newest"
+	SortMostViewed = "views"
+	SortMostLiked  = "likes"
+)
 
-	if category != "" {
-		where["category"] = category
+// ArticleFilter 文章列表的筛选条件，空值表示不限制
+type ArticleFilter struct {
+	Category     string
+	Tag          string
+	Author       string
+	FeaturedOnly bool
+	Sort         string
+}
+
+// ArticleCursor 游标分页的位置，记录上一页最后一篇文章的排序值
+type ArticleCursor struct {
+	Sort  string    `json:"s"`
+	Date  time.Time `json:"d,omitempty"`
+	Count int       `json:"c,omitempty"`
+	ID    int64     `json:"i"`
+}
+
+// sortColumn 排序字段，按发布时间之外的字段排序时再以 id 保证顺序稳定
+func (f ArticleFilter) sortColumn() string {
+	switch f.Sort {
+	case SortMostViewed:
+		return "views"
+	case SortMostLiked:
+		return "likes"
+	default:
+		return "date"
 	}
+}
 
-	if featuredOnly {
+// conditions 将筛选条件转为 WHERE 子句
+func (f ArticleFilter) conditions() (string, []interface{}) {
+	where := make(map[string]interface{})
+	if f.Category != "" {
+		where["category"] = f.Category
+	}
+	if f.Author != "" {
+		where["author"] = f.Author
+	}
+	if f.FeaturedOnly {
 		where["featured"] = true
 	}
 
+	keys := make([]string, 0, len(where))
+	for k := range where {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	clauses := []string{"1=1"}
+	params := make([]interface{}, 0, len(where)+1)
+	for _, k := range keys {
+		clauses = append(clauses, k+" = ?")
+		params = append(params, where[k])
+	}
+
 	// 处理标签过滤
-	tagFilter := ""
-	if tag != "" {
-		tagFilter = " AND JSON_CONTAINS(tags, JSON_ARRAY(?))"
+	if f.Tag != "" {
+		clauses = append(clauses, "JSON_CONTAINS(tags, JSON_ARRAY(?))")
+		params = append(params, f.Tag)
 	}
+	return strings.Join(clauses, " AND "), params
+}
 
-	// 构建基础查询
-	query := fmt.Sprintf("SELECT * FROM articles WHERE 1=1%s ORDER BY date DESC LIMIT ? OFFSET ?", tagFilter)
+// CountArticles 统计符合筛选条件的文章数量
+func (a *ArticleData) CountArticles(filter ArticleFilter) (int64, error) {
+	conditions, params := filter.conditions()
 
-	// 准备参数
-	params := []interface{}{}
-	if tag != "" {
-		params = append(params, tag)
+	var total int64
+	err := a.db.QueryRow("SELECT COUNT(*) FROM articles WHERE "+conditions, params...).Scan(&total)
+	if err != nil {
+		return 0, fmt.Errorf("统计文章数量失败: %w", err)
 	}
+	return total, nil
+}
+
+// ListArticles 按筛选条件分页查询文章，cursor 不为空时从游标之后继续并忽略 offset
+func (a *ArticleData) ListArticles(filter ArticleFilter, limit, offset int, cursor *ArticleCursor) ([]*Article, error) {
+	conditions, params := filter.conditions()
+	column := filter.sortColumn()
+
+	if cursor != nil {
+		var value interface{} = cursor.Count
+		if column == "date" {
+			value = cursor.Date
+		}
+		conditions += fmt.Sprintf(" AND (%s < ? OR (%s = ? AND id < ?))", column, column)
+		params = append(params, value, value, cursor.ID)
+		offset = 0
+	}
+
+	// 构建基础查询
+	query := fmt.Sprintf("SELECT %s FROM articles WHERE %s ORDER BY %s DESC, id DESC LIMIT ? OFFSET ?",
+		articleListSelect, conditions, column)
 	params = append(params, limit, offset)
 
 	// 执行!
@@ -288,24 +463,178 @@ func (a *ArticleData) ListArticles(limit, offset int, category, tag string, feat
 	return a.scanArticles(rows)
 }
 
-func (a *ArticleData) SearchArticles(query string, limit int) ([]*Article, error) {
-	searchQuery := `
-			SELECT * FROM articles 
-			WHERE MATCH(title, excerpt, content) AGAINST(? IN NATURAL LANGUAGE MODE)
-			ORDER BY MATCH(title, excerpt, content) AGAINST(? IN NATURAL LANGUAGE MODE) DESC
-			LIMIT ?
-		`
+// CursorAfter 生成指向某篇文章之后的游标
+func (f ArticleFilter) CursorAfter(article *Article) *ArticleCursor {
+	cursor := &ArticleCursor{Sort: f.Sort, ID: article.ID}
+	switch f.sortColumn() {
+	case "views":
+		cursor.Count = article.Views
+	case "likes":
+		cursor.Count = article.Likes
+	default:
+		cursor.Date = article.Date
+	}
+	return cursor
+}
+
+// SearchResult 搜索命中的文章，Content 为正文原文，供生成摘要片段使用
+type SearchResult struct {
+	*Article
+	Score float64 `json:"score"`
+}
+
+// SearchArticles 按相关度搜索文章，全文索引不可用或关键词过短时使用 LIKE 匹配
+func (a *ArticleData) SearchArticles(query string, filter ArticleFilter, limit, offset int) ([]*SearchResult, int64, error) {
+	// ngram 默认按两个字切分，单个字无法通过全文索引命中
+	if a.fullText && utf8.RuneCountInString(query) >= 2 {
+		return a.searchFullText(query, filter, limit, offset)
+	}
+	return a.searchLike(query, filter, limit, offset)
+}
+
+func (a *ArticleData) searchFullText(query string, filter ArticleFilter, limit, offset int) ([]*SearchResult, int64, error) {
+	conditions, params := filter.conditions()
+	match := "MATCH(title, excerpt, content) AGAINST(? IN NATURAL LANGUAGE MODE)"
+
+	var total int64
+	countParams := append([]interface{}{query}, params...)
+	err := a.db.QueryRow("SELECT COUNT(*) FROM articles WHERE "+match+" AND "+conditions, countParams...).Scan(&total)
+	if err != nil {
+		return nil, 0, fmt.Errorf("统计搜索结果失败: %w", err)
+	}
+
+	searchQuery := fmt.Sprintf(`
+			SELECT %s, content, %s AS score FROM articles
+			WHERE %s AND %s
+			ORDER BY score DESC, date DESC
+			LIMIT ? OFFSET ?
+		`, articleListSelect, match, match, conditions)
 
 	// 准备参数
-	params := []interface{}{query, query, limit}
+	searchParams := []interface{}{query, query}
+	searchParams = append(searchParams, params...)
+	searchParams = append(searchParams, limit, offset)
 
-	rows, err := a.db.Query(searchQuery, params...)
+	rows, err := a.db.Query(searchQuery, searchParams...)
 	if err != nil {
-		return nil, fmt.Errorf("搜索文章失败: %w", err)
+		return nil, 0, fmt.Errorf("搜索文章失败: %w", err)
 	}
 	defer rows.Close()
 
-	return a.scanArticles(rows)
+	results, err := a.scanSearchResults(rows)
+	return results, total, err
+}
+
+// searchLike 每个关键词都必须出现在标题、摘要或正文中，标题命中的权重最高
+func (a *ArticleData) searchLike(query string, filter ArticleFilter, limit, offset int) ([]*SearchResult, int64, error) {
+	conditions, params := filter.conditions()
+
+	terms := SearchTerms(query)
+	if len(terms) == 0 {
+		return nil, 0, nil
+	}
+
+	var matchClauses, scoreClauses []string
+	var matchParams, scoreParams []interface{}
+	for _, term := range terms {
+		pattern := "%" + escapeLike(term) + "%"
+		matchClauses = append(matchClauses, `(title LIKE ? OR excerpt LIKE ? OR content LIKE ?)`)
+		matchParams = append(matchParams, pattern, pattern, pattern)
+		scoreClauses = append(scoreClauses, `(title LIKE ?) * 3 + (excerpt LIKE ?) * 2 + (content LIKE ?)`)
+		scoreParams = append(scoreParams, pattern, pattern, pattern)
+	}
+	where := strings.Join(matchClauses, " AND ") + " AND " + conditions
+	whereParams := append(matchParams, params...)
+
+	var total int64
+	err := a.db.QueryRow("SELECT COUNT(*) FROM articles WHERE "+where, whereParams...).Scan(&total)
+	if err != nil {
+		return nil, 0, fmt.Errorf("统计搜索结果失败: %w", err)
+	}
+
+	searchQuery := fmt.Sprintf(`
+			SELECT %s, content, %s AS score FROM articles
+			WHERE %s
+			ORDER BY score DESC, date DESC
+			LIMIT ? OFFSET ?
+		`, articleListSelect, strings.Join(scoreClauses, " + "), where)
+
+	searchParams := append(scoreParams, whereParams...)
+	searchParams = append(searchParams, limit, offset)
+
+	rows, err := a.db.Query(searchQuery, searchParams...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("搜索文章失败: %w", err)
+	}
+	defer rows.Close()
+
+	results, err := a.scanSearchResults(rows)
+	return results, total, err
+}
+
+// SearchTerms 按空白拆分关键词，去重并限制数量
+func SearchTerms(query string) []string {
+	var terms []string
+	seen := make(map[string]bool)
+	for _, term := range strings.Fields(query) {
+		lower := strings.ToLower(term)
+		if seen[lower] {
+			continue
+		}
+		seen[lower] = true
+		terms = append(terms, term)
+		if len(terms) == 5 {
+			break
+		}
+	}
+	return terms
+}
+
+// escapeLike 转义 LIKE 中的通配符
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+func (a *ArticleData) scanSearchResults(rows *sql.Rows) ([]*SearchResult, error) {
+	var results []*SearchResult
+
+	for rows.Next() {
+		var article Article
+		var tagsJSON string
+		var score float64
+
+		err := rows.Scan(
+			&article.ID,
+			&article.Title,
+			&article.Excerpt,
+			&article.Author,
+			&article.Date,
+			&article.ReadTime,
+			&article.Likes,
+			&article.Comments,
+			&article.Views,
+			&article.Category,
+			&tagsJSON,
+			&article.Featured,
+			&article.Content,
+			&score,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("解析文章数据失败: %w", err)
+		}
+
+		if err := json.Unmarshal([]byte(tagsJSON), &article.Tags); err != nil {
+			return nil, fmt.Errorf("解析标签失败: %w", err)
+		}
+
+		results = append(results, &SearchResult{Article: &article, Score: score})
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("遍历结果集失败: %w", err)
+	}
+
+	return results, nil
 }
 
 // scanArticles 从查询结果中扫描文章列表
@@ -350,7 +679,7 @@ func (a *ArticleData) scanArticles(rows *sql.Rows) ([]*Article, error) {
 }
 
 func (a *ArticleData) GetRecentArticles(limit int) ([]*Article, error) {
-	query := "SELECT * FROM articles ORDER BY date DESC LIMIT ?"
+	query := "SELECT " + articleListSelect + " FROM articles ORDER BY date DESC LIMIT ?"
 	rows, err := a.db.Query(query, limit)
 	if err != nil {
 		return nil, fmt.Errorf("获取最新文章失败: %w", err)
@@ -365,7 +694,7 @@ func (a *ArticleData) GetFeaturedArticles(limit int) ([]*Article, error) {
 		"featured": true,
 	}
 
-	rows, err := a.db.Select("articles", nil, where)
+	rows, err := a.db.Select("articles", articleListColumns, where)
 	if err != nil {
 		return nil, fmt.Errorf("获取精选文章失败: %w", err)
 	}
@@ -379,7 +708,7 @@ func (a *ArticleData) GetArticlesByCategory(category string, limit int) ([]*Arti
 		"category": category,
 	}
 
-	rows, err := a.db.Select("articles", nil, where)
+	rows, err := a.db.Select("articles", articleListColumns, where)
 	if err != nil {
 		return nil, fmt.Errorf("获取分类文章失败: %w", err)
 	}
@@ -0,0 +1,69 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+)
+
+// DeleteArticlesData 删除文章的修订历史、表情回应、点赞收藏和评论，文章本身由调用方删除；
+// 某一类数据清理失败时继续清理其余部分，返回合并后的错误
+func DeleteArticlesData(articleIDs []int64) error {
+	if len(articleIDs) == 0 {
+		return nil
+	}
+
+	steps := []struct {
+		name string
+		run  func() error
+	}{
+		{"修订历史", func() error {
+			revisionsDB, err := UseRevisionData()
+			if err != nil {
+				return err
+			}
+			defer revisionsDB.Close()
+			return eachArticle(articleIDs, revisionsDB.DeleteArticleRevisions)
+		}},
+		{"表情回应", func() error {
+			reactionsDB, err := UseReactionData()
+			if err != nil {
+				return err
+			}
+			defer reactionsDB.Close()
+			return eachArticle(articleIDs, reactionsDB.DeleteArticleReactions)
+		}},
+		{"点赞收藏", func() error {
+			engagementDB, err := UseEngagementData()
+			if err != nil {
+				return err
+			}
+			defer engagementDB.Close()
+			return eachArticle(articleIDs, engagementDB.DeleteArticleEngagement)
+		}},
+		{"评论", func() error {
+			commentsDB, err := UseCommentData()
+			if err != nil {
+				return err
+			}
+			defer commentsDB.Close()
+			return eachArticle(articleIDs, commentsDB.DeleteArticleComments)
+		}},
+	}
+
+	var errs []error
+	for _, step := range steps {
+		if err := step.run(); err != nil {
+			errs = append(errs, fmt.Errorf("清理%s失败: %w", step.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func eachArticle(articleIDs []int64, fn func(int64) error) error {
+	for _, id := range articleIDs {
+		if err := fn(id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
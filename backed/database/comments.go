@@ -0,0 +1,426 @@
+package database
+
+import (
+	"Backed/utils"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Comment 文章评论，回复通过 parent_id 嵌套，root_id 指向所在楼层的顶层评论
+type Comment struct {
+	ID         int64      `json:"id"`
+	ArticleID  int64      `json:"article_id"`
+	ParentID   *int64     `json:"parent_id"`
+	RootID     *int64     `json:"root_id"`
+	UserID     int64      `json:"user_id,string"`
+	Content    string     `json:"content"`
+	Deleted    bool       `json:"deleted"`
+	CreatedAt  time.Time  `json:"created_at"`
+	EditedAt   *time.Time `json:"edited_at"`
+	ReplyCount int        `json:"reply_count"`
+}
+
+type CommentData struct {
+	db *utils.Database
+}
+
+const commentColumns = "id, article_id, parent_id, root_id, user_id, content, deleted, created_at, edited_at"
+
+func UseCommentData() (*CommentData, error) {
+	data, err := utils.UseDatabase("config.yaml")
+	if err != nil {
+		return nil, fmt.Errorf("无法打开数据库: %v", err)
+	}
+
+	commentTableColumns := []utils.ColumnDefinition{
+		{Name: "id", Type: "BIGINT", Primary: true},
+		{Name: "article_id", Type: "BIGINT", Nullable: false},
+		{Name: "parent_id", Type: "BIGINT", Nullable: true},
+		{Name: "root_id", Type: "BIGINT", Nullable: true},
+		{Name: "user_id", Type: "BIGINT", Nullable: false},
+		{Name: "content", Type: "TEXT", Nullable: false},
+		{Name: "deleted", Type: "BOOLEAN", Default: "FALSE"},
+		{Name: "created_at", Type: "TIMESTAMP", Default: "CURRENT_TIMESTAMP"},
+		{Name: "edited_at", Type: "TIMESTAMP NULL", Nullable: true},
+	}
+	if err := data.CreateTable("article_comments", commentTableColumns); err != nil {
+		return nil, fmt.Errorf("无法创建评论数据表: %v", err)
+	}
+
+	indexes := []struct{ name, columns string }{
+		{"idx_comment_article", "article_id, root_id, created_at"},
+		{"idx_comment_root", "root_id, created_at"},
+		{"idx_comment_parent", "parent_id"},
+		{"idx_comment_user", "user_id"},
+	}
+	for _, index := range indexes {
+		if err := createIndex(data, "article_comments", index.name, "", index.columns); err != nil {
+			return nil, err
+		}
+	}
+
+	return &CommentData{db: data}, nil
+}
+
+func (d *CommentData) Close() error {
+	if d.db != nil {
+		return d.db.Close()
+	}
+	return nil
+}
+
+// AddComment 发表评论并增加文章评论数；回复的评论不存在、已删除或不属于该文章时返回 sql.ErrNoRows
+func (d *CommentData) AddComment(comment *Comment) (*Comment, error) {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if comment.ParentID != nil {
+		var articleID int64
+		var rootID sql.NullInt64
+		var deleted bool
+		err := tx.QueryRow(
+			"SELECT article_id, root_id, deleted FROM article_comments WHERE id = ? FOR UPDATE",
+			*comment.ParentID,
+		).Scan(&articleID, &rootID, &deleted)
+		if err != nil {
+			return nil, err
+		}
+		if articleID != comment.ArticleID || deleted {
+			return nil, sql.ErrNoRows
+		}
+
+		root := *comment.ParentID
+		if rootID.Valid {
+			root = rootID.Int64
+		}
+		comment.RootID = &root
+	}
+
+	comment.CreatedAt = time.Now()
+	result, err := tx.Exec(
+		"INSERT INTO article_comments (article_id, parent_id, root_id, user_id, content, created_at) VALUES (?, ?, ?, ?, ?, ?)",
+		comment.ArticleID, comment.ParentID, comment.RootID, comment.UserID, comment.Content, comment.CreatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("保存评论失败: %w", err)
+	}
+	if comment.ID, err = result.LastInsertId(); err != nil {
+		return nil, err
+	}
+
+	// 评论数与评论记录在同一事务中修改
+	result, err = tx.Exec("UPDATE articles SET comments = comments + 1 WHERE id = ?", comment.ArticleID)
+	if err != nil {
+		return nil, fmt.Errorf("修改评论数失败: %w", err)
+	}
+	if affected, err := result.RowsAffected(); err != nil {
+		return nil, err
+	} else if affected == 0 {
+		return nil, sql.ErrNoRows
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return comment, nil
+}
+
+func (d *CommentData) GetComment(id int64) (*Comment, error) {
+	rows, err := d.db.Query("SELECT "+commentColumns+" FROM article_comments WHERE id = ?", id)
+	if err != nil {
+		return nil, fmt.Errorf("查询评论失败: %w", err)
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return nil, sql.ErrNoRows
+	}
+	return scanComment(rows, false)
+}
+
+// UpdateComment 修改评论内容，已删除的评论不能修改
+func (d *CommentData) UpdateComment(id int64, content string) (*time.Time, error) {
+	editedAt := time.Now()
+	result, err := d.db.Exec(
+		"UPDATE article_comments SET content = ?, edited_at = ? WHERE id = ? AND deleted = FALSE",
+		content, editedAt, id,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("修改评论失败: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	if affected == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return &editedAt, nil
+}
+
+// DeleteComment 删除评论并减少文章评论数。
+// 有回复的评论只清空内容保留楼层，没有回复的直接删除，并顺带删除因此失去全部回复的已删除上级评论
+func (d *CommentData) DeleteComment(id int64) (bool, error) {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	var articleID int64
+	var parentID sql.NullInt64
+	var deleted bool
+	err = tx.QueryRow(
+		"SELECT article_id, parent_id, deleted FROM article_comments WHERE id = ? FOR UPDATE", id,
+	).Scan(&articleID, &parentID, &deleted)
+	if err != nil {
+		return false, err
+	}
+	if deleted {
+		return false, nil
+	}
+
+	hasReplies, err := commentHasReplies(tx, id)
+	if err != nil {
+		return false, err
+	}
+	if hasReplies {
+		_, err = tx.Exec("UPDATE article_comments SET deleted = TRUE, content = '' WHERE id = ?", id)
+	} else {
+		_, err = tx.Exec("DELETE FROM article_comments WHERE id = ?", id)
+		if err == nil {
+			err = pruneDeletedAncestors(tx, parentID)
+		}
+	}
+	if err != nil {
+		return false, fmt.Errorf("删除评论失败: %w", err)
+	}
+
+	if _, err := tx.Exec("UPDATE articles SET comments = GREATEST(comments - 1, 0) WHERE id = ?", articleID); err != nil {
+		return false, fmt.Errorf("修改评论数失败: %w", err)
+	}
+	return true, tx.Commit()
+}
+
+func commentHasReplies(tx *sql.Tx, id int64) (bool, error) {
+	var exists bool
+	err := tx.QueryRow("SELECT EXISTS(SELECT 1 FROM article_comments WHERE parent_id = ?)", id).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("查询回复失败: %w", err)
+	}
+	return exists, nil
+}
+
+// pruneDeletedAncestors 沿回复链向上删除已删除且不再有回复的评论
+func pruneDeletedAncestors(tx *sql.Tx, parentID sql.NullInt64) error {
+	for parentID.Valid {
+		id := parentID.Int64
+		var deleted bool
+		err := tx.QueryRow(
+			"SELECT parent_id, deleted FROM article_comments WHERE id = ? FOR UPDATE", id,
+		).Scan(&parentID, &deleted)
+		if err == sql.ErrNoRows {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if !deleted {
+			return nil
+		}
+
+		hasReplies, err := commentHasReplies(tx, id)
+		if err != nil || hasReplies {
+			return err
+		}
+		if _, err := tx.Exec("DELETE FROM article_comments WHERE id = ?", id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ListComments 分页返回文章的顶层评论，按时间倒序，附带每层的回复数
+func (d *CommentData) ListComments(articleID int64, limit, offset int) ([]*Comment, int64, error) {
+	var total int64
+	err := d.db.QueryRow(
+		"SELECT COUNT(*) FROM article_comments WHERE article_id = ? AND root_id IS NULL", articleID,
+	).Scan(&total)
+	if err != nil {
+		return nil, 0, fmt.Errorf("统计评论失败: %w", err)
+	}
+
+	rows, err := d.db.Query(
+		"SELECT "+commentColumns+`,
+			(SELECT COUNT(*) FROM article_comments r WHERE r.root_id = article_comments.id)
+		FROM article_comments WHERE article_id = ? AND root_id IS NULL
+		ORDER BY created_at DESC, id DESC LIMIT ? OFFSET ?`,
+		articleID, limit, offset,
+	)
+	if err != nil {
+		return nil, 0, fmt.Errorf("查询评论失败: %w", err)
+	}
+	defer rows.Close()
+
+	comments, err := scanComments(rows, true)
+	return comments, total, err
+}
+
+// ListReplies 分页返回一层楼中的全部回复，按时间正序，客户端根据 parent_id 组织嵌套
+func (d *CommentData) ListReplies(rootID int64, limit, offset int) ([]*Comment, int64, error) {
+	var total int64
+	err := d.db.QueryRow("SELECT COUNT(*) FROM article_comments WHERE root_id = ?", rootID).Scan(&total)
+	if err != nil {
+		return nil, 0, fmt.Errorf("统计回复失败: %w", err)
+	}
+
+	rows, err := d.db.Query(
+		"SELECT "+commentColumns+" FROM article_comments WHERE root_id = ? ORDER BY created_at, id LIMIT ? OFFSET ?",
+		rootID, limit, offset,
+	)
+	if err != nil {
+		return nil, 0, fmt.Errorf("查询回复失败: %w", err)
+	}
+	defer rows.Close()
+
+	comments, err := scanComments(rows, false)
+	return comments, total, err
+}
+
+// ListUserComments 用户发表的全部未删除评论，用于数据导出
+func (d *CommentData) ListUserComments(userID int64) ([]*Comment, error) {
+	rows, err := d.db.Query(
+		"SELECT "+commentColumns+" FROM article_comments WHERE user_id = ? AND deleted = FALSE ORDER BY created_at",
+		userID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("查询评论失败: %w", err)
+	}
+	defer rows.Close()
+
+	return scanComments(rows, false)
+}
+
+func (d *CommentData) DeleteArticleComments(articleID int64) error {
+	_, err := d.db.Exec("DELETE FROM article_comments WHERE article_id = ?", articleID)
+	return err
+}
+
+// DeleteUserComments 删除用户的全部评论并同步扣减评论数，别人回复过的评论只清空内容
+func (d *CommentData) DeleteUserComments(userID int64) error {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`UPDATE articles a JOIN (
+			SELECT article_id, COUNT(*) AS n FROM article_comments
+			WHERE user_id = ? AND deleted = FALSE GROUP BY article_id
+		) c ON c.article_id = a.id
+		SET a.comments = GREATEST(a.comments - c.n, 0)`, userID)
+	if err != nil {
+		return fmt.Errorf("修改评论数失败: %w", err)
+	}
+	if _, err := tx.Exec("UPDATE article_comments SET deleted = TRUE, content = '' WHERE user_id = ?", userID); err != nil {
+		return fmt.Errorf("删除评论失败: %w", err)
+	}
+
+	// 自己回复自己时会形成链条，逐层删除没有回复的评论，并记下它们回复的评论
+	parents := make(map[int64]bool)
+	for {
+		rows, err := tx.Query(`SELECT c.parent_id FROM article_comments c
+			LEFT JOIN article_comments r ON r.parent_id = c.id
+			WHERE c.user_id = ? AND r.id IS NULL AND c.parent_id IS NOT NULL
+			FOR UPDATE`, userID)
+		if err != nil {
+			return fmt.Errorf("查询评论失败: %w", err)
+		}
+		for rows.Next() {
+			var parentID int64
+			if err := rows.Scan(&parentID); err != nil {
+				rows.Close()
+				return err
+			}
+			parents[parentID] = true
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("遍历结果集失败: %w", err)
+		}
+
+		result, err := tx.Exec(`DELETE c FROM article_comments c
+			LEFT JOIN article_comments r ON r.parent_id = c.id
+			WHERE c.user_id = ? AND r.id IS NULL`, userID)
+		if err != nil {
+			return fmt.Errorf("删除评论失败: %w", err)
+		}
+		if affected, err := result.RowsAffected(); err != nil {
+			return err
+		} else if affected == 0 {
+			break
+		}
+	}
+
+	// 被删除的评论可能回复的是其他用户已删除的评论，这些评论失去最后一条回复后同样清理
+	for parentID := range parents {
+		if err := pruneDeletedAncestors(tx, sql.NullInt64{Int64: parentID, Valid: true}); err != nil {
+			return fmt.Errorf("删除评论失败: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+func scanComments(rows *sql.Rows, withReplyCount bool) ([]*Comment, error) {
+	comments := []*Comment{}
+	for rows.Next() {
+		comment, err := scanComment(rows, withReplyCount)
+		if err != nil {
+			return nil, err
+		}
+		comments = append(comments, comment)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("遍历结果集失败: %w", err)
+	}
+	return comments, nil
+}
+
+func scanComment(rows *sql.Rows, withReplyCount bool) (*Comment, error) {
+	var comment Comment
+	var parentID, rootID sql.NullInt64
+	var editedAt sql.NullTime
+
+	dest := []interface{}{
+		&comment.ID,
+		&comment.ArticleID,
+		&parentID,
+		&rootID,
+		&comment.UserID,
+		&comment.Content,
+		&comment.Deleted,
+		&comment.CreatedAt,
+		&editedAt,
+	}
+	if withReplyCount {
+		dest = append(dest, &comment.ReplyCount)
+	}
+	if err := rows.Scan(dest...); err != nil {
+		return nil, fmt.Errorf("解析评论数据失败: %w", err)
+	}
+
+	if parentID.Valid {
+		comment.ParentID = &parentID.Int64
+	}
+	if rootID.Valid {
+		comment.RootID = &rootID.Int64
+	}
+	if editedAt.Valid {
+		comment.EditedAt = &editedAt.Time
+	}
+	return &comment, nil
+}
@@ -0,0 +1,32 @@
+// Package daltest 为测试提供替代 PostgreSQL 的数据库
+package daltest
+
+import (
+	"Backed/database/dal"
+	"path/filepath"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// SQLite 在临时目录中创建 SQLite 数据库并迁移 models，替换 dal.PostgreSQL，测试结束后恢复
+func SQLite(t *testing.T, models ...interface{}) *gorm.DB {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(models...); err != nil {
+		t.Fatal(err)
+	}
+
+	old := dal.PostgreSQL
+	dal.PostgreSQL = db
+	t.Cleanup(func() { dal.PostgreSQL = old })
+	return db
+}
@@ -19,5 +19,45 @@ func InitMySQL(cfg config.DBConfig) error {
 		return err
 	}
 	PostgreSQL = db
-	return PostgreSQL.AutoMigrate(&model.User{}, &model.VerificationToken{})
+	err = PostgreSQL.AutoMigrate(
+		&model.User{},
+		&model.VerificationToken{},
+		&model.UserBlock{},
+		&model.Report{},
+		&model.AuditLog{},
+		&model.DataExport{},
+		&model.AccountDeletion{},
+		&model.AttachmentBlob{},
+		&model.Attachment{},
+		&model.CallRecord{},
+		&model.E2EEDevice{},
+		&model.OneTimePreKey{},
+		&model.Envelope{},
+		&model.E2EEMessage{},
+		&model.E2EEMention{},
+		&model.ConversationRetention{},
+		&model.PushToken{},
+		&model.PushSetting{},
+		&model.PushMute{},
+	)
+	if err != nil {
+		return err
+	}
+	return protectAuditLogs()
+}
+
+// protectAuditLogs 在数据库层面禁止修改或删除审计日志
+func protectAuditLogs() error {
+	return PostgreSQL.Exec(`
+		CREATE OR REPLACE FUNCTION audit_logs_append_only() RETURNS trigger AS $$
+		BEGIN
+			RAISE EXCEPTION 'audit_logs is append-only';
+		END;
+		$$ LANGUAGE plpgsql;
+
+		DROP TRIGGER IF EXISTS audit_logs_append_only ON audit_logs;
+		CREATE TRIGGER audit_logs_append_only
+			BEFORE UPDATE OR DELETE OR TRUNCATE ON audit_logs
+			FOR EACH STATEMENT EXECUTE FUNCTION audit_logs_append_only();
+	`).Error
 }
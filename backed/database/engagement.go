@@ -0,0 +1,306 @@
+package database
+
+import (
+	"Backed/utils"
+	"fmt"
+	"log"
+	"time"
+)
+
+// ViewWindow 同一访客在这段时间内重复打开文章只计一次浏览
+const ViewWindow = 30 * time.Minute
+
+// UserArticle 用户点赞或收藏过的文章，用于数据导出
+type UserArticle struct {
+	ArticleID int64     `json:"article_id"`
+	Title     string    `json:"title"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type EngagementData struct {
+	db *utils.Database
+}
+
+func UseEngagementData() (*EngagementData, error) {
+	data, err := utils.UseDatabase("config.yaml")
+	if err != nil {
+		return nil, fmt.Errorf("无法打开数据库: %v", err)
+	}
+
+	relationColumns := []utils.ColumnDefinition{
+		{Name: "id", Type: "BIGINT", Primary: true},
+		{Name: "article_id", Type: "BIGINT", Nullable: false},
+		{Name: "user_id", Type: "BIGINT", Nullable: false},
+		{Name: "created_at", Type: "TIMESTAMP", Default: "CURRENT_TIMESTAMP"},
+	}
+	if err := data.CreateTable("article_likes", relationColumns); err != nil {
+		return nil, fmt.Errorf("无法创建点赞数据表: %v", err)
+	}
+	if err := data.CreateTable("article_bookmarks", relationColumns); err != nil {
+		return nil, fmt.Errorf("无法创建收藏数据表: %v", err)
+	}
+
+	viewColumns := []utils.ColumnDefinition{
+		{Name: "id", Type: "BIGINT", Primary: true},
+		{Name: "article_id", Type: "BIGINT", Nullable: false},
+		{Name: "viewer", Type: "VARCHAR(64)", Nullable: false},
+		{Name: "viewed_at", Type: "TIMESTAMP", Default: "CURRENT_TIMESTAMP"},
+	}
+	if err := data.CreateTable("article_views", viewColumns); err != nil {
+		return nil, fmt.Errorf("无法创建浏览记录数据表: %v", err)
+	}
+
+	indexes := []struct{ table, name, kind, columns string }{
+		{"article_likes", "uk_article_like", "UNIQUE", "article_id, user_id"},
+		{"article_likes", "idx_like_user", "", "user_id"},
+		{"article_bookmarks", "uk_article_bookmark", "UNIQUE", "article_id, user_id"},
+		{"article_bookmarks", "idx_bookmark_user", "", "user_id, created_at"},
+		{"article_views", "uk_article_view", "UNIQUE", "article_id, viewer"},
+		{"article_views", "idx_view_time", "", "viewed_at"},
+	}
+	for _, index := range indexes {
+		if err := createIndex(data, index.table, index.name, index.kind, index.columns); err != nil {
+			return nil, err
+		}
+	}
+
+	return &EngagementData{db: data}, nil
+}
+
+func (e *EngagementData) Close() error {
+	if e.db != nil {
+		return e.db.Close()
+	}
+	return nil
+}
+
+// Like 点赞，重复点赞返回 false，计数与点赞记录在同一事务中修改
+func (e *EngagementData) Like(articleID, userID int64) (bool, error) {
+	return e.changeLike(
+		"INSERT IGNORE INTO article_likes (article_id, user_id) VALUES (?, ?)",
+		"UPDATE articles SET likes = likes + 1 WHERE id = ?",
+		articleID, userID,
+	)
+}
+
+// Unlike 取消点赞，未点赞过返回 false
+func (e *EngagementData) Unlike(articleID, userID int64) (bool, error) {
+	return e.changeLike(
+		"DELETE FROM article_likes WHERE article_id = ? AND user_id = ?",
+		"UPDATE articles SET likes = GREATEST(likes - 1, 0) WHERE id = ?",
+		articleID, userID,
+	)
+}
+
+func (e *EngagementData) changeLike(relationQuery, counterQuery string, articleID, userID int64) (bool, error) {
+	tx, err := e.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(relationQuery, articleID, userID)
+	if err != nil {
+		return false, fmt.Errorf("修改点赞失败: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if affected == 0 {
+		return false, nil
+	}
+
+	if _, err := tx.Exec(counterQuery, articleID); err != nil {
+		return false, fmt.Errorf("修改点赞数失败: %w", err)
+	}
+	return true, tx.Commit()
+}
+
+// GetLikes 文章当前的点赞数
+func (e *EngagementData) GetLikes(articleID int64) (int, error) {
+	var likes int
+	err := e.db.QueryRow("SELECT likes FROM articles WHERE id = ?", articleID).Scan(&likes)
+	return likes, err
+}
+
+// AddBookmark 收藏文章，重复收藏返回 false
+func (e *EngagementData) AddBookmark(articleID, userID int64) (bool, error) {
+	result, err := e.db.Exec("INSERT IGNORE INTO article_bookmarks (article_id, user_id) VALUES (?, ?)", articleID, userID)
+	if err != nil {
+		return false, fmt.Errorf("收藏文章失败: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// RemoveBookmark 取消收藏，未收藏过返回 false
+func (e *EngagementData) RemoveBookmark(articleID, userID int64) (bool, error) {
+	result, err := e.db.Exec("DELETE FROM article_bookmarks WHERE article_id = ? AND user_id = ?", articleID, userID)
+	if err != nil {
+		return false, fmt.Errorf("取消收藏失败: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// UserState 用户是否点赞、收藏了文章
+func (e *EngagementData) UserState(articleID, userID int64) (liked, bookmarked bool, err error) {
+	err = e.db.QueryRow(`SELECT
+			EXISTS(SELECT 1 FROM article_likes WHERE article_id = ? AND user_id = ?),
+			EXISTS(SELECT 1 FROM article_bookmarks WHERE article_id = ? AND user_id = ?)`,
+		articleID, userID, articleID, userID,
+	).Scan(&liked, &bookmarked)
+	if err != nil {
+		return false, false, fmt.Errorf("查询点赞收藏状态失败: %w", err)
+	}
+	return liked, bookmarked, nil
+}
+
+// ListBookmarks 按收藏时间倒序返回用户收藏的文章，不包含正文
+func (e *EngagementData) ListBookmarks(userID int64, limit, offset int) ([]*Article, int64, error) {
+	var total int64
+	err := e.db.QueryRow(
+		"SELECT COUNT(*) FROM article_bookmarks b JOIN articles a ON a.id = b.article_id WHERE b.user_id = ?",
+		userID,
+	).Scan(&total)
+	if err != nil {
+		return nil, 0, fmt.Errorf("统计收藏失败: %w", err)
+	}
+
+	columns := ""
+	for i, col := range articleListColumns {
+		if i > 0 {
+			columns += ", "
+		}
+		columns += "a." + col
+	}
+	rows, err := e.db.Query(
+		"SELECT "+columns+" FROM article_bookmarks b JOIN articles a ON a.id = b.article_id "+
+			"WHERE b.user_id = ? ORDER BY b.created_at DESC, b.id DESC LIMIT ? OFFSET ?",
+		userID, limit, offset,
+	)
+	if err != nil {
+		return nil, 0, fmt.Errorf("查询收藏失败: %w", err)
+	}
+	defer rows.Close()
+
+	articles, err := (&ArticleData{}).scanArticles(rows)
+	return articles, total, err
+}
+
+// RecordView 记录一次浏览，同一访客在 ViewWindow 内重复访问时返回 false
+func (e *EngagementData) RecordView(articleID int64, viewer string) (bool, error) {
+	// 插入返回 1，窗口外刷新时间返回 2，窗口内不修改返回 0
+	result, err := e.db.Exec(
+		`INSERT INTO article_views (article_id, viewer, viewed_at) VALUES (?, ?, ?)
+		ON DUPLICATE KEY UPDATE viewed_at = IF(viewed_at < ?, VALUES(viewed_at), viewed_at)`,
+		articleID, viewer, time.Now(), time.Now().Add(-ViewWindow),
+	)
+	if err != nil {
+		return false, fmt.Errorf("记录浏览失败: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// ListUserLikes 用户点赞过的全部文章，按点赞时间排列
+func (e *EngagementData) ListUserLikes(userID int64) ([]UserArticle, error) {
+	return e.listUserArticles("article_likes", userID)
+}
+
+// ListUserBookmarks 用户收藏的全部文章，按收藏时间排列
+func (e *EngagementData) ListUserBookmarks(userID int64) ([]UserArticle, error) {
+	return e.listUserArticles("article_bookmarks", userID)
+}
+
+func (e *EngagementData) listUserArticles(table string, userID int64) ([]UserArticle, error) {
+	rows, err := e.db.Query(
+		"SELECT r.article_id, a.title, r.created_at FROM "+table+" r JOIN articles a ON a.id = r.article_id "+
+			"WHERE r.user_id = ? ORDER BY r.created_at, r.id",
+		userID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("查询%s失败: %w", table, err)
+	}
+	defer rows.Close()
+
+	items := []UserArticle{}
+	for rows.Next() {
+		var item UserArticle
+		if err := rows.Scan(&item.ArticleID, &item.Title, &item.CreatedAt); err != nil {
+			return nil, fmt.Errorf("解析%s失败: %w", table, err)
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// DeleteArticleEngagement 文章删除后清理其点赞、收藏和浏览记录
+func (e *EngagementData) DeleteArticleEngagement(articleID int64) error {
+	for _, table := range []string{"article_likes", "article_bookmarks", "article_views"} {
+		if _, err := e.db.Exec("DELETE FROM "+table+" WHERE article_id = ?", articleID); err != nil {
+			return fmt.Errorf("清理%s失败: %w", table, err)
+		}
+	}
+	return nil
+}
+
+// DeleteUserEngagement 撤销用户的全部点赞并删除收藏，用于注销账号
+func (e *EngagementData) DeleteUserEngagement(userID int64) error {
+	tx, err := e.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`UPDATE articles a JOIN article_likes l ON l.article_id = a.id
+		SET a.likes = GREATEST(a.likes - 1, 0) WHERE l.user_id = ?`, userID)
+	if err != nil {
+		return fmt.Errorf("撤销点赞数失败: %w", err)
+	}
+	if _, err := tx.Exec("DELETE FROM article_likes WHERE user_id = ?", userID); err != nil {
+		return fmt.Errorf("删除点赞失败: %w", err)
+	}
+	if _, err := tx.Exec("DELETE FROM article_bookmarks WHERE user_id = ?", userID); err != nil {
+		return fmt.Errorf("删除收藏失败: %w", err)
+	}
+	// 浏览记录以用户ID作为访客标识
+	if _, err := tx.Exec("DELETE FROM article_views WHERE viewer = ?", UserViewer(userID)); err != nil {
+		return fmt.Errorf("删除浏览记录失败: %w", err)
+	}
+	return tx.Commit()
+}
+
+// UserViewer 登录用户的访客标识，未登录时使用 IP
+func UserViewer(userID int64) string {
+	return fmt.Sprintf("user:%d", userID)
+}
+
+// IPViewer 未登录访客的标识
+func IPViewer(ip string) string {
+	return "ip:" + ip
+}
+
+// CleanupViewRecordsTask 定期删除窗口外的浏览记录，这些记录已不再影响去重
+func CleanupViewRecordsTask() {
+	ticker := time.NewTicker(1 * time.Hour)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		engagementDB, err := UseEngagementData()
+		if err != nil {
+			log.Printf("清理浏览记录失败: %s", err)
+			continue
+		}
+		result, err := engagementDB.db.Exec("DELETE FROM article_views WHERE viewed_at < ?", time.Now().Add(-ViewWindow))
+		engagementDB.Close()
+		if err != nil {
+			log.Printf("清理浏览记录失败: %s", err)
+			continue
+		}
+		if n, _ := result.RowsAffected(); n > 0 {
+			log.Printf("已清理 %d 条过期浏览记录", n)
+		}
+	}
+}
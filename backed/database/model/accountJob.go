@@ -0,0 +1,35 @@
+package model
+
+import (
+	"time"
+)
+
+// 数据导出任务状态
+const (
+	ExportPending    = "pending"
+	ExportProcessing = "processing"
+	ExportDone       = "done"
+	ExportFailed     = "failed"
+)
+
+// DataExport 用户数据导出任务
+type DataExport struct {
+	ID         int64      `gorm:"primaryKey;column:id" json:"id"`
+	UserID     int64      `gorm:"not null;index;column:user_id" json:"-"`
+	User       User       `gorm:"constraint:OnDelete:CASCADE;" json:"-"`
+	Status     string     `gorm:"not null;default:pending;size:16;index;column:status" json:"status"`
+	FilePath   string     `gorm:"not null;default:'';column:file_path" json:"-"`
+	Error      string     `gorm:"not null;default:'';column:error" json:"error,omitempty"`
+	CreatedAt  time.Time  `gorm:"autoCreateTime;column:created_at" json:"created_at"`
+	StartedAt  *time.Time `gorm:"column:started_at" json:"started_at"`
+	FinishedAt *time.Time `gorm:"column:finished_at" json:"finished_at"`
+	ExpiresAt  *time.Time `gorm:"column:expires_at" json:"expires_at"`
+}
+
+// AccountDeletion 账号注销申请，冷静期结束后由后台任务删除账号
+type AccountDeletion struct {
+	UserID      int64     `gorm:"primaryKey;autoIncrement:false;column:user_id" json:"-"`
+	User        User      `gorm:"constraint:OnDelete:CASCADE;" json:"-"`
+	RequestedAt time.Time `gorm:"not null;column:requested_at" json:"requested_at"`
+	ScheduledAt time.Time `gorm:"not null;index;column:scheduled_at" json:"scheduled_at"`
+}
@@ -0,0 +1,35 @@
+package model
+
+import (
+	"time"
+)
+
+// 附件类型
+const (
+	AttachmentImage = "image"
+	AttachmentFile  = "file"
+	AttachmentVoice = "voice"
+)
+
+// AttachmentBlob 按内容哈希去重后的实际文件
+type AttachmentBlob struct {
+	Hash        string    `gorm:"primaryKey;size:64;column:hash"`
+	Size        int64     `gorm:"not null;column:size"`
+	ContentType string    `gorm:"not null;size:128;column:content_type"`
+	StorageKey  string    `gorm:"not null;column:storage_key"`
+	CreatedAt   time.Time `gorm:"autoCreateTime;column:created_at"`
+}
+
+// Attachment 用户上传的一个附件，多个附件可以引用同一个文件
+type Attachment struct {
+	ID         int64          `gorm:"primaryKey;column:id" json:"id"`
+	UploaderID int64          `gorm:"not null;index;column:uploader_id" json:"uploader_id,string"`
+	Uploader   User           `gorm:"foreignKey:UploaderID;references:UserID;constraint:OnDelete:CASCADE;" json:"-"`
+	BlobHash   string         `gorm:"not null;size:64;index;column:blob_hash" json:"-"`
+	Blob       AttachmentBlob `gorm:"foreignKey:BlobHash;references:Hash;constraint:OnDelete:RESTRICT;" json:"-"`
+	Kind       string         `gorm:"not null;size:16;column:kind" json:"kind"`
+	FileName   string         `gorm:"not null;default:'';size:255;column:file_name" json:"file_name"`
+	PeerID     *int64         `gorm:"column:peer_id" json:"peer_id,string"`
+	CreatedAt  time.Time      `gorm:"autoCreateTime;column:created_at" json:"created_at"`
+	ExpiresAt  *time.Time     `gorm:"index;column:expires_at" json:"expires_at"`
+}
@@ -0,0 +1,19 @@
+package model
+
+import (
+	"time"
+)
+
+// AuditLog 审计日志，只允许追加，每条记录的哈希包含上一条记录的哈希以防篡改
+type AuditLog struct {
+	ID         int64     `gorm:"primaryKey;column:id" json:"id"`
+	ActorID    *int64    `gorm:"index;column:actor_id" json:"actor_id,string"`
+	Action     string    `gorm:"not null;size:64;index;column:action" json:"action"`
+	TargetType string    `gorm:"not null;default:'';size:32;column:target_type" json:"target_type"`
+	TargetID   string    `gorm:"not null;default:'';size:64;index;column:target_id" json:"target_id"`
+	IP         string    `gorm:"not null;default:'';size:64;column:ip" json:"ip"`
+	Detail     string    `gorm:"not null;default:'';column:detail" json:"detail"`
+	CreatedAt  time.Time `gorm:"not null;index;column:created_at" json:"created_at"`
+	PrevHash   string    `gorm:"not null;size:64;column:prev_hash" json:"prev_hash"`
+	Hash       string    `gorm:"not null;size:64;uniqueIndex;column:hash" json:"hash"`
+}
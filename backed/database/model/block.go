@@ -0,0 +1,14 @@
+package model
+
+import (
+	"time"
+)
+
+// UserBlock 拉黑关系，BlockerID 拉黑了 BlockedID
+type UserBlock struct {
+	BlockerID int64     `gorm:"primaryKey;autoIncrement:false;column:blocker_id" json:"blocker_id,string"`
+	BlockedID int64     `gorm:"primaryKey;autoIncrement:false;index;column:blocked_id" json:"blocked_id,string"`
+	Blocker   User      `gorm:"foreignKey:BlockerID;references:UserID;constraint:OnDelete:CASCADE;" json:"-"`
+	Blocked   User      `gorm:"foreignKey:BlockedID;references:UserID;constraint:OnDelete:CASCADE;" json:"-"`
+	CreatedAt time.Time `gorm:"autoCreateTime;column:created_at" json:"created_at"`
+}
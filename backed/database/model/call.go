@@ -0,0 +1,40 @@
+package model
+
+import (
+	"time"
+)
+
+// 通话类型
+const (
+	CallAudio = "audio"
+	CallVideo = "video"
+)
+
+// 通话结果
+const (
+	CallCompleted = "completed" // 接通后挂断
+	CallMissed    = "missed"    // 无人接听、主叫取消或被叫不在线
+	CallRejected  = "rejected"  // 被叫拒绝
+	CallBusy      = "busy"      // 被叫正在通话中
+)
+
+// CallRecord 一次通话的记录
+type CallRecord struct {
+	ID         string     `gorm:"primaryKey;size:32;column:id" json:"id"`
+	CallerID   int64      `gorm:"not null;index;column:caller_id" json:"caller_id,string"`
+	Caller     User       `gorm:"foreignKey:CallerID;references:UserID;constraint:OnDelete:CASCADE;" json:"-"`
+	CalleeID   int64      `gorm:"not null;index;column:callee_id" json:"callee_id,string"`
+	Callee     User       `gorm:"foreignKey:CalleeID;references:UserID;constraint:OnDelete:CASCADE;" json:"-"`
+	Media      string     `gorm:"not null;size:8;column:media" json:"media"`
+	Outcome    string     `gorm:"not null;size:16;column:outcome" json:"outcome"`
+	StartedAt  time.Time  `gorm:"not null;index;column:started_at" json:"started_at"`
+	AnsweredAt *time.Time `gorm:"column:answered_at" json:"answered_at"`
+	EndedAt    time.Time  `gorm:"not null;column:ended_at" json:"ended_at"`
+	Duration   int        `gorm:"not null;default:0;column:duration" json:"duration"` // 接通时长(秒)
+	MissedRead bool       `gorm:"not null;default:false;column:missed_read" json:"-"` // 被叫是否已查看未接来电
+}
+
+// IsMissedFor 判断该通话对用户而言是否为未接来电
+func (r *CallRecord) IsMissedFor(userID int64) bool {
+	return r.CalleeID == userID && (r.Outcome == CallMissed || r.Outcome == CallBusy)
+}
@@ -0,0 +1,70 @@
+package model
+
+import (
+	"time"
+)
+
+// E2EEDevice 用户的一台端到端加密设备及其身份密钥和签名预密钥
+type E2EEDevice struct {
+	UserID          int64     `gorm:"primaryKey;autoIncrement:false;column:user_id" json:"-"`
+	User            User      `gorm:"constraint:OnDelete:CASCADE;" json:"-"`
+	DeviceID        int       `gorm:"primaryKey;autoIncrement:false;column:device_id" json:"device_id"`
+	Name            string    `gorm:"not null;default:'';size:64;column:name" json:"name"`
+	IdentityKey     string    `gorm:"not null;column:identity_key" json:"identity_key"`
+	SignedPreKeyID  int       `gorm:"not null;column:signed_pre_key_id" json:"-"`
+	SignedPreKey    string    `gorm:"not null;column:signed_pre_key" json:"-"`
+	SignedPreKeySig string    `gorm:"not null;column:signed_pre_key_sig" json:"-"`
+	CreatedAt       time.Time `gorm:"autoCreateTime;column:created_at" json:"created_at"`
+	UpdatedAt       time.Time `gorm:"autoUpdateTime;column:updated_at" json:"updated_at"`
+}
+
+// OneTimePreKey 一次性预密钥，被其他用户获取后即删除
+type OneTimePreKey struct {
+	UserID    int64  `gorm:"primaryKey;autoIncrement:false;column:user_id"`
+	User      User   `gorm:"constraint:OnDelete:CASCADE;"`
+	DeviceID  int    `gorm:"primaryKey;autoIncrement:false;column:device_id"`
+	KeyID     int    `gorm:"primaryKey;autoIncrement:false;column:key_id"`
+	PublicKey string `gorm:"not null;column:public_key"`
+	Signature string `gorm:"not null;default:'';column:signature"`
+}
+
+// Envelope 发给某台设备的密文，服务器只负责暂存和投递，无法解密
+type Envelope struct {
+	ID              int64      `gorm:"primaryKey;column:id" json:"id"`
+	SenderID        int64      `gorm:"not null;index;column:sender_id" json:"sender_id,string"`
+	SenderDevice    int        `gorm:"not null;column:sender_device" json:"sender_device"`
+	MessageID       int64      `gorm:"not null;default:0;index;column:message_id" json:"message_id"`
+	RecipientID     int64      `gorm:"not null;index:idx_envelope_inbox,priority:1;column:recipient_id" json:"-"`
+	Recipient       User       `gorm:"foreignKey:RecipientID;references:UserID;constraint:OnDelete:CASCADE;" json:"-"`
+	RecipientDevice int        `gorm:"not null;index:idx_envelope_inbox,priority:2;column:recipient_device" json:"-"`
+	Type            int        `gorm:"not null;column:type" json:"type"`
+	Content         []byte     `gorm:"not null;column:content" json:"content"`
+	CreatedAt       time.Time  `gorm:"autoCreateTime;index;column:created_at" json:"created_at"`
+	ExpiresAt       *time.Time `gorm:"index;column:expires_at" json:"expires_at"`
+}
+
+// E2EEMessage 一次发送的元数据，发给各设备的密文共用同一个消息ID；
+// 内容只存在于密文中，服务器据此判断撤回和编辑的权限与时限；
+// 回复和转发引用的均为原消息的ID，引用的消息过期删除后由客户端显示为不可用
+type E2EEMessage struct {
+	ID              int64      `gorm:"primaryKey;column:id" json:"id"`
+	SenderID        int64      `gorm:"not null;index;column:sender_id" json:"sender_id,string"`
+	Sender          User       `gorm:"foreignKey:SenderID;references:UserID;constraint:OnDelete:CASCADE;" json:"-"`
+	RecipientID     int64      `gorm:"not null;index;column:recipient_id" json:"recipient_id,string"`
+	Recipient       User       `gorm:"foreignKey:RecipientID;references:UserID;constraint:OnDelete:CASCADE;" json:"-"`
+	ReplacesID      *int64     `gorm:"index;column:replaces_id" json:"replaces_id,omitempty"` // 编辑后的新版本指向原消息
+	ReplyToID       *int64     `gorm:"index;column:reply_to_id" json:"reply_to_id,omitempty"`
+	ForwardedFromID *int64     `gorm:"column:forwarded_from_id" json:"forwarded_from_id,omitempty"`
+	CreatedAt       time.Time  `gorm:"autoCreateTime;column:created_at" json:"created_at"`
+	EditedAt        *time.Time `gorm:"column:edited_at" json:"edited_at,omitempty"`
+	RecalledAt      *time.Time `gorm:"index;column:recalled_at" json:"recalled_at,omitempty"`
+	ExpiresAt       *time.Time `gorm:"index;column:expires_at" json:"-"`
+}
+
+// E2EEMention 消息提及的用户，由客户端在发送时声明；编辑后的提及记在原消息上
+type E2EEMention struct {
+	MessageID int64       `gorm:"primaryKey;autoIncrement:false;column:message_id"`
+	Message   E2EEMessage `gorm:"constraint:OnDelete:CASCADE;"`
+	UserID    int64       `gorm:"primaryKey;autoIncrement:false;index;column:user_id"`
+	User      User        `gorm:"constraint:OnDelete:CASCADE;"`
+}
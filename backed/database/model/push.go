@@ -0,0 +1,51 @@
+package model
+
+import (
+	"time"
+)
+
+// 推送平台
+const (
+	PushWeb  = "web"
+	PushFCM  = "fcm"
+	PushAPNs = "apns"
+)
+
+// PushToken 设备的推送令牌，Web Push 的令牌为订阅地址
+type PushToken struct {
+	ID        int64     `gorm:"primaryKey;column:id" json:"id"`
+	UserID    int64     `gorm:"not null;index;column:user_id" json:"-"`
+	User      User      `gorm:"constraint:OnDelete:CASCADE;" json:"-"`
+	Platform  string    `gorm:"not null;size:16;uniqueIndex:idx_push_token,priority:1;column:platform" json:"platform"`
+	Token     string    `gorm:"not null;uniqueIndex:idx_push_token,priority:2;column:token" json:"token"`
+	P256dh    string    `gorm:"not null;default:'';column:p256dh" json:"-"`
+	Auth      string    `gorm:"not null;default:'';column:auth" json:"-"`
+	CreatedAt time.Time `gorm:"autoCreateTime;column:created_at" json:"created_at"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime;column:updated_at" json:"updated_at"`
+}
+
+// PushSetting 用户的推送偏好，免打扰时段按分钟表示，允许跨越零点
+type PushSetting struct {
+	UserID     int64     `gorm:"primaryKey;autoIncrement:false;column:user_id" json:"-"`
+	User       User      `gorm:"constraint:OnDelete:CASCADE;" json:"-"`
+	Enabled    bool      `gorm:"not null;default:true;column:enabled" json:"enabled"`
+	DNDEnabled bool      `gorm:"not null;default:false;column:dnd_enabled" json:"dnd_enabled"`
+	DNDStart   int       `gorm:"not null;default:1320;column:dnd_start" json:"dnd_start"`
+	DNDEnd     int       `gorm:"not null;default:480;column:dnd_end" json:"dnd_end"`
+	Timezone   string    `gorm:"not null;default:'Asia/Shanghai';size:64;column:timezone" json:"timezone"`
+	UpdatedAt  time.Time `gorm:"autoUpdateTime;column:updated_at" json:"updated_at"`
+}
+
+// PushMute 对某个会话静音，Until 为空表示一直静音
+type PushMute struct {
+	UserID    int64      `gorm:"primaryKey;autoIncrement:false;column:user_id" json:"-"`
+	User      User       `gorm:"constraint:OnDelete:CASCADE;" json:"-"`
+	PeerID    int64      `gorm:"primaryKey;autoIncrement:false;column:peer_id" json:"peer_id,string"`
+	Until     *time.Time `gorm:"column:until" json:"until"`
+	CreatedAt time.Time  `gorm:"autoCreateTime;column:created_at" json:"created_at"`
+}
+
+// IsActive 静音是否仍然有效
+func (m *PushMute) IsActive(now time.Time) bool {
+	return m.Until == nil || m.Until.After(now)
+}
@@ -0,0 +1,28 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// 举报处理状态
+const (
+	ReportPending  = "pending"
+	ReportResolved = "resolved"
+	ReportRejected = "rejected"
+)
+
+// Report 举报记录，进入管理员审核队列
+type Report struct {
+	ID         int64                      `gorm:"primaryKey;column:id" json:"id"`
+	ReporterID int64                      `gorm:"not null;index;column:reporter_id" json:"reporter_id,string"`
+	TargetID   int64                      `gorm:"not null;index;column:target_id" json:"target_id,string"`
+	MessageIDs datatypes.JSONSlice[int64] `gorm:"column:message_ids" json:"message_ids"`
+	Reason     string                     `gorm:"not null;size:1000;column:reason" json:"reason"`
+	Status     string                     `gorm:"not null;default:pending;size:16;index;column:status" json:"status"`
+	HandleNote string                     `gorm:"not null;default:'';size:1000;column:handle_note" json:"handle_note"`
+	HandledBy  *int64                     `gorm:"column:handled_by" json:"handled_by,string"`
+	HandledAt  *time.Time                 `gorm:"column:handled_at" json:"handled_at"`
+	CreatedAt  time.Time                  `gorm:"autoCreateTime;column:created_at" json:"created_at"`
+}
@@ -0,0 +1,16 @@
+package model
+
+import (
+	"time"
+)
+
+// ConversationRetention 私聊会话的消息保留时长，双方共用一份设置，UserLow 为较小的用户ID
+type ConversationRetention struct {
+	UserLow   int64     `gorm:"primaryKey;autoIncrement:false;column:user_low" json:"-"`
+	UserHigh  int64     `gorm:"primaryKey;autoIncrement:false;column:user_high" json:"-"`
+	Low       User      `gorm:"foreignKey:UserLow;references:UserID;constraint:OnDelete:CASCADE;" json:"-"`
+	High      User      `gorm:"foreignKey:UserHigh;references:UserID;constraint:OnDelete:CASCADE;" json:"-"`
+	Seconds   int       `gorm:"not null;default:0;column:seconds" json:"seconds"`
+	UpdatedBy int64     `gorm:"not null;column:updated_by" json:"updated_by,string"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime;column:updated_at" json:"updated_at"`
+}
@@ -14,4 +14,17 @@ type User struct {
 	IsAdmin   bool       `gorm:"not null;default:false;column:is_admin"`
 	CreatedAt time.Time  `gorm:"autoCreateTime;column:created_at"`
 	LastLogin *time.Time `gorm:"autoUpdateTime;column:last_login"`
+
+	AvatarVersion    string     `gorm:"not null;default:'';size:16;column:avatar_version"` // 为空表示未设置头像
+	BanReason        string     `gorm:"not null;default:'';size:500;column:ban_reason"`
+	BannedUntil      *time.Time `gorm:"column:banned_until"`       // 为空表示永久封禁
+	TokensValidAfter *time.Time `gorm:"column:tokens_valid_after"` // 早于此时间签发的令牌失效
+}
+
+// IsBanActive 判断封禁是否仍在生效
+func (u *User) IsBanActive(now time.Time) bool {
+	if !u.IsBanned {
+		return false
+	}
+	return u.BannedUntil == nil || now.Before(*u.BannedUntil)
 }
@@ -0,0 +1,285 @@
+package database
+
+import (
+	"Backed/utils"
+	"fmt"
+	"math/rand/v2"
+	"time"
+)
+
+// reactionCounterShards 每个表情的计数拆分为多行，热门内容的并发点赞不会争抢同一行锁
+const reactionCounterShards = 8
+
+type ReactionCount struct {
+	Emoji string `json:"emoji"`
+	Count int64  `json:"count"`
+}
+
+type Reactor struct {
+	UserID    int64     `json:"user_id,string"`
+	Emoji     string    `json:"emoji"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// UserReaction 用户在某篇文章上的一个表情回应，用于数据导出
+type UserReaction struct {
+	ArticleID int64     `json:"article_id"`
+	Emoji     string    `json:"emoji"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type ReactionData struct {
+	db *utils.Database
+}
+
+func UseReactionData() (*ReactionData, error) {
+	data, err := utils.UseDatabase("config.yaml")
+	if err != nil {
+		return nil, fmt.Errorf("无法打开数据库: %v", err)
+	}
+
+	reactionTableColumns := []utils.ColumnDefinition{
+		{Name: "id", Type: "BIGINT", Primary: true},
+		{Name: "article_id", Type: "BIGINT", Nullable: false},
+		{Name: "user_id", Type: "BIGINT", Nullable: false},
+		{Name: "emoji", Type: "VARCHAR(32)", Nullable: false},
+		{Name: "created_at", Type: "TIMESTAMP", Default: "CURRENT_TIMESTAMP"},
+	}
+	if err := data.CreateTable("article_reactions", reactionTableColumns); err != nil {
+		return nil, fmt.Errorf("无法创建表情回应数据表: %v", err)
+	}
+
+	counterTableColumns := []utils.ColumnDefinition{
+		{Name: "id", Type: "BIGINT", Primary: true},
+		{Name: "article_id", Type: "BIGINT", Nullable: false},
+		{Name: "emoji", Type: "VARCHAR(32)", Nullable: false},
+		{Name: "shard", Type: "TINYINT", Nullable: false},
+		{Name: "count", Type: "BIGINT", Default: "0"},
+	}
+	if err := data.CreateTable("article_reaction_counts", counterTableColumns); err != nil {
+		return nil, fmt.Errorf("无法创建表情计数数据表: %v", err)
+	}
+
+	if err := createIndex(data, "article_reactions", "uk_reaction", "UNIQUE", "article_id, user_id, emoji"); err != nil {
+		return nil, err
+	}
+	if err := createIndex(data, "article_reaction_counts", "uk_reaction_shard", "UNIQUE", "article_id, emoji, shard"); err != nil {
+		return nil, err
+	}
+
+	return &ReactionData{db: data}, nil
+}
+
+// createIndex 索引不存在时创建，kind 为空表示普通索引
+func createIndex(db *utils.Database, table, name, kind, columns string) error {
+	checkQuery := `
+        SELECT COUNT(*) FROM information_schema.statistics 
+        WHERE table_schema = DATABASE() 
+        AND table_name = ? 
+        AND index_name = ?
+    `
+
+	var count int
+	if err := db.QueryRow(checkQuery, table, name).Scan(&count); err != nil {
+		return fmt.Errorf("检查索引失败: %w", err)
+	}
+	if count > 0 {
+		return nil
+	}
+
+	createQuery := fmt.Sprintf("ALTER TABLE %s ADD %s INDEX %s (%s)", table, kind, name, columns)
+	if _, err := db.Exec(createQuery); err != nil {
+		return fmt.Errorf("创建索引失败: %w", err)
+	}
+	return nil
+}
+
+func (r *ReactionData) Close() error {
+	if r.db != nil {
+		return r.db.Close()
+	}
+	return nil
+}
+
+// AddReaction 添加表情回应，重复添加返回 false
+func (r *ReactionData) AddReaction(articleID, userID int64, emoji string) (bool, error) {
+	return r.changeReaction(
+		"INSERT IGNORE INTO article_reactions (article_id, user_id, emoji) VALUES (?, ?, ?)",
+		articleID, userID, emoji, 1,
+	)
+}
+
+// RemoveReaction 取消表情回应，未回应过返回 false
+func (r *ReactionData) RemoveReaction(articleID, userID int64, emoji string) (bool, error) {
+	return r.changeReaction(
+		"DELETE FROM article_reactions WHERE article_id = ? AND user_id = ? AND emoji = ?",
+		articleID, userID, emoji, -1,
+	)
+}
+
+// changeReaction 在同一事务中修改回应记录和随机一个计数分片
+func (r *ReactionData) changeReaction(query string, articleID, userID int64, emoji string, delta int) (bool, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(query, articleID, userID, emoji)
+	if err != nil {
+		return false, fmt.Errorf("修改表情回应失败: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if affected == 0 {
+		return false, nil
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO article_reaction_counts (article_id, emoji, shard, count) VALUES (?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE count = count + VALUES(count)`,
+		articleID, emoji, rand.IntN(reactionCounterShards), delta)
+	if err != nil {
+		return false, fmt.Errorf("更新表情计数失败: %w", err)
+	}
+
+	return true, tx.Commit()
+}
+
+// GetCounts 汇总各表情的回应数量
+func (r *ReactionData) GetCounts(articleID int64) ([]ReactionCount, error) {
+	rows, err := r.db.Query(`
+		SELECT emoji, SUM(count) AS total FROM article_reaction_counts
+		WHERE article_id = ?
+		GROUP BY emoji
+		HAVING total > 0
+		ORDER BY total DESC`, articleID)
+	if err != nil {
+		return nil, fmt.Errorf("查询表情计数失败: %w", err)
+	}
+	defer rows.Close()
+
+	counts := []ReactionCount{}
+	for rows.Next() {
+		var count ReactionCount
+		if err := rows.Scan(&count.Emoji, &count.Count); err != nil {
+			return nil, fmt.Errorf("解析表情计数失败: %w", err)
+		}
+		counts = append(counts, count)
+	}
+	return counts, rows.Err()
+}
+
+// GetUserReactions 查询用户对文章做出的回应
+func (r *ReactionData) GetUserReactions(articleID, userID int64) ([]string, error) {
+	rows, err := r.db.Query(
+		"SELECT emoji FROM article_reactions WHERE article_id = ? AND user_id = ? ORDER BY id",
+		articleID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("查询表情回应失败: %w", err)
+	}
+	defer rows.Close()
+
+	emojis := []string{}
+	for rows.Next() {
+		var emoji string
+		if err := rows.Scan(&emoji); err != nil {
+			return nil, fmt.Errorf("解析表情回应失败: %w", err)
+		}
+		emojis = append(emojis, emoji)
+	}
+	return emojis, rows.Err()
+}
+
+// CountUserReactions 统计用户对文章使用了多少种表情
+func (r *ReactionData) CountUserReactions(articleID, userID int64) (int, error) {
+	var count int
+	err := r.db.QueryRow(
+		"SELECT COUNT(*) FROM article_reactions WHERE article_id = ? AND user_id = ?",
+		articleID, userID).Scan(&count)
+	return count, err
+}
+
+// ListReactors 分页列出回应过的用户，emoji 为空表示全部表情
+func (r *ReactionData) ListReactors(articleID int64, emoji string, limit, offset int) ([]Reactor, error) {
+	query := "SELECT user_id, emoji, created_at FROM article_reactions WHERE article_id = ?"
+	params := []interface{}{articleID}
+	if emoji != "" {
+		query += " AND emoji = ?"
+		params = append(params, emoji)
+	}
+	query += " ORDER BY id DESC LIMIT ? OFFSET ?"
+	params = append(params, limit, offset)
+
+	rows, err := r.db.Query(query, params...)
+	if err != nil {
+		return nil, fmt.Errorf("查询回应用户失败: %w", err)
+	}
+	defer rows.Close()
+
+	reactors := []Reactor{}
+	for rows.Next() {
+		var reactor Reactor
+		if err := rows.Scan(&reactor.UserID, &reactor.Emoji, &reactor.CreatedAt); err != nil {
+			return nil, fmt.Errorf("解析回应用户失败: %w", err)
+		}
+		reactors = append(reactors, reactor)
+	}
+	return reactors, rows.Err()
+}
+
+// ListReactionsByUser 用户的全部表情回应，按回应时间排列
+func (r *ReactionData) ListReactionsByUser(userID int64) ([]UserReaction, error) {
+	rows, err := r.db.Query(
+		"SELECT article_id, emoji, created_at FROM article_reactions WHERE user_id = ? ORDER BY created_at, id",
+		userID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("查询表情回应失败: %w", err)
+	}
+	defer rows.Close()
+
+	reactions := []UserReaction{}
+	for rows.Next() {
+		var reaction UserReaction
+		if err := rows.Scan(&reaction.ArticleID, &reaction.Emoji, &reaction.CreatedAt); err != nil {
+			return nil, fmt.Errorf("解析表情回应失败: %w", err)
+		}
+		reactions = append(reactions, reaction)
+	}
+	return reactions, rows.Err()
+}
+
+// DeleteArticleReactions 删除文章的全部回应
+func (r *ReactionData) DeleteArticleReactions(articleID int64) error {
+	for _, table := range []string{"article_reactions", "article_reaction_counts"} {
+		if _, err := r.db.Delete(table, map[string]interface{}{"article_id": articleID}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeleteUserReactions 删除用户的全部回应并同步扣减计数
+func (r *ReactionData) DeleteUserReactions(userID int64) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		INSERT INTO article_reaction_counts (article_id, emoji, shard, count)
+		SELECT article_id, emoji, 0, -COUNT(*) FROM article_reactions WHERE user_id = ? GROUP BY article_id, emoji
+		ON DUPLICATE KEY UPDATE count = count + VALUES(count)`, userID)
+	if err != nil {
+		return fmt.Errorf("扣减表情计数失败: %w", err)
+	}
+
+	if _, err := tx.Exec("DELETE FROM article_reactions WHERE user_id = ?", userID); err != nil {
+		return fmt.Errorf("删除表情回应失败: %w", err)
+	}
+	return tx.Commit()
+}
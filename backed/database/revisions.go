@@ -0,0 +1,254 @@
+package database
+
+import (
+	"Backed/utils"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+type Revision struct {
+	ID           int64     `json:"id"`
+	ArticleID    int64     `json:"article_id"`
+	Revision     int       `json:"revision"`
+	Title        string    `json:"title"`
+	Excerpt      string    `json:"excerpt"`
+	Content      string    `json:"content,omitempty"`
+	Tags         []string  `json:"tags"`
+	Editor       string    `json:"editor"`
+	RestoredFrom *int      `json:"restored_from"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+type RevisionData struct {
+	db *utils.Database
+}
+
+func UseRevisionData() (*RevisionData, error) {
+	data, err := utils.UseDatabase("config.yaml")
+	if err != nil {
+		return nil, fmt.Errorf("无法打开数据库: %v", err)
+	}
+
+	revisionTableColumns := []utils.ColumnDefinition{
+		{Name: "id", Type: "BIGINT", Primary: true},
+		{Name: "article_id", Type: "BIGINT", Nullable: false},
+		{Name: "revision", Type: "INT", Nullable: false},
+		{Name: "title", Type: "VARCHAR(255)", Nullable: false},
+		{Name: "excerpt", Type: "TEXT"},
+		{Name: "content", Type: "MEDIUMTEXT"},
+		{Name: "tags", Type: "JSON"},
+		{Name: "editor", Type: "VARCHAR(255)", Nullable: false},
+		{Name: "restored_from", Type: "INT", Nullable: true},
+		{Name: "created_at", Type: "TIMESTAMP", Default: "CURRENT_TIMESTAMP"},
+	}
+	if err := data.CreateTable("article_revisions", revisionTableColumns); err != nil {
+		return nil, fmt.Errorf("无法创建文章修订数据表: %v", err)
+	}
+
+	if err := createIndex(data, "article_revisions", "uk_article_revision", "UNIQUE", "article_id, revision"); err != nil {
+		return nil, err
+	}
+
+	return &RevisionData{db: data}, nil
+}
+
+func (r *RevisionData) Close() error {
+	if r.db != nil {
+		return r.db.Close()
+	}
+	return nil
+}
+
+// AddRevision 保存文章当前内容为新的修订版本，版本号在文章内递增
+func (r *RevisionData) AddRevision(article *Article, editor string, restoredFrom *int) (*Revision, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	revision, err := insertRevision(tx, article, editor, restoredFrom)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return revision, nil
+}
+
+// SaveArticle 在同一事务中保存文章的修改并记录修订版本，record 为 false 表示内容未变化，不产生新版本。
+// 更新会锁住文章行，并发的编辑依次提交，最新的修订版本始终与文章的当前内容一致
+func (r *RevisionData) SaveArticle(original, article *Article, editor string, restoredFrom *int, record bool) (*Revision, error) {
+	tagsJSON, err := json.Marshal(article.Tags)
+	if err != nil {
+		return nil, fmt.Errorf("序列化标签失败: %w", err)
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// 点赞、评论、浏览数由原子操作维护，这里不覆盖，避免编辑时丢失并发的计数
+	_, err = tx.Exec(
+		`UPDATE articles SET title = ?, excerpt = ?, author = ?, date = ?, read_time = ?, category = ?,
+		tags = ?, featured = ?, content = ?, content_html = ? WHERE id = ?`,
+		article.Title, article.Excerpt, article.Author, article.Date, article.ReadTime, article.Category,
+		string(tagsJSON), article.Featured, article.Content, article.ContentHTML, article.ID,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// 功能上线前发布的文章没有修订记录，首次编辑时先保存原始版本
+	var count int
+	err = tx.QueryRow("SELECT COUNT(*) FROM article_revisions WHERE article_id = ?", article.ID).Scan(&count)
+	if err != nil {
+		return nil, fmt.Errorf("查询修订版本失败: %w", err)
+	}
+	if count == 0 {
+		if _, err := insertRevision(tx, original, original.Author, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	var revision *Revision
+	if record {
+		if revision, err = insertRevision(tx, article, editor, restoredFrom); err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return revision, nil
+}
+
+// insertRevision 在事务中写入新的修订版本
+func insertRevision(tx *sql.Tx, article *Article, editor string, restoredFrom *int) (*Revision, error) {
+	tagsJSON, err := json.Marshal(article.Tags)
+	if err != nil {
+		return nil, fmt.Errorf("序列化标签失败: %w", err)
+	}
+
+	// 锁住该文章的修订记录，避免并发编辑得到相同的版本号
+	var latest int
+	err = tx.QueryRow(
+		"SELECT COALESCE(MAX(revision), 0) FROM article_revisions WHERE article_id = ? FOR UPDATE",
+		article.ID,
+	).Scan(&latest)
+	if err != nil {
+		return nil, fmt.Errorf("查询修订版本失败: %w", err)
+	}
+
+	revision := &Revision{
+		ArticleID:    article.ID,
+		Revision:     latest + 1,
+		Title:        article.Title,
+		Excerpt:      article.Excerpt,
+		Content:      article.Content,
+		Tags:         article.Tags,
+		Editor:       editor,
+		RestoredFrom: restoredFrom,
+		CreatedAt:    time.Now(),
+	}
+	result, err := tx.Exec(
+		`INSERT INTO article_revisions (article_id, revision, title, excerpt, content, tags, editor, restored_from, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		revision.ArticleID, revision.Revision, revision.Title, revision.Excerpt, revision.Content,
+		string(tagsJSON), revision.Editor, revision.RestoredFrom, revision.CreatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("保存修订版本失败: %w", err)
+	}
+	if revision.ID, err = result.LastInsertId(); err != nil {
+		return nil, err
+	}
+	return revision, nil
+}
+
+// ListRevisions 文章的修订历史，不包含正文
+func (r *RevisionData) ListRevisions(articleID int64) ([]*Revision, error) {
+	rows, err := r.db.Query(
+		`SELECT id, article_id, revision, title, excerpt, tags, editor, restored_from, created_at
+		FROM article_revisions WHERE article_id = ? ORDER BY revision DESC`,
+		articleID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("查询修订历史失败: %w", err)
+	}
+	defer rows.Close()
+
+	var revisions []*Revision
+	for rows.Next() {
+		revision, err := scanRevision(rows, false)
+		if err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, revision)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("遍历结果集失败: %w", err)
+	}
+	return revisions, nil
+}
+
+// GetRevision 获取指定版本的完整内容
+func (r *RevisionData) GetRevision(articleID int64, revision int) (*Revision, error) {
+	rows, err := r.db.Query(
+		`SELECT id, article_id, revision, title, excerpt, tags, editor, restored_from, created_at, content
+		FROM article_revisions WHERE article_id = ? AND revision = ?`,
+		articleID, revision,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("查询修订版本失败: %w", err)
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return nil, sql.ErrNoRows
+	}
+	return scanRevision(rows, true)
+}
+
+func (r *RevisionData) DeleteArticleRevisions(articleID int64) error {
+	_, err := r.db.Exec("DELETE FROM article_revisions WHERE article_id = ?", articleID)
+	return err
+}
+
+func scanRevision(rows *sql.Rows, withContent bool) (*Revision, error) {
+	var revision Revision
+	var tagsJSON string
+	var restoredFrom sql.NullInt64
+
+	dest := []interface{}{
+		&revision.ID,
+		&revision.ArticleID,
+		&revision.Revision,
+		&revision.Title,
+		&revision.Excerpt,
+		&tagsJSON,
+		&revision.Editor,
+		&restoredFrom,
+		&revision.CreatedAt,
+	}
+	if withContent {
+		dest = append(dest, &revision.Content)
+	}
+	if err := rows.Scan(dest...); err != nil {
+		return nil, fmt.Errorf("解析修订数据失败: %w", err)
+	}
+
+	if err := json.Unmarshal([]byte(tagsJSON), &revision.Tags); err != nil {
+		return nil, fmt.Errorf("解析标签失败: %w", err)
+	}
+	if restoredFrom.Valid {
+		from := int(restoredFrom.Int64)
+		revision.RestoredFrom = &from
+	}
+	return &revision, nil
+}
@@ -0,0 +1,150 @@
+package accout
+
+import (
+	"Backed/database"
+	"Backed/database/dal"
+	"Backed/database/model"
+	"Backed/utils/audit"
+	"Backed/utils/avatar"
+	"context"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm/clause"
+)
+
+// RequestDeletion 申请注销账号，返回实际删除时间
+func RequestDeletion(userID int64) (*model.AccountDeletion, error) {
+	now := time.Now()
+	deletion := model.AccountDeletion{
+		UserID:      userID,
+		RequestedAt: now,
+		ScheduledAt: now.Add(time.Duration(accountCfg.DeletionGraceDays) * 24 * time.Hour),
+	}
+
+	// 重复申请不会推迟已有的删除时间
+	err := dal.PostgreSQL.Clauses(clause.OnConflict{DoNothing: true}).Create(&deletion).Error
+	if err != nil {
+		return nil, err
+	}
+	if err := dal.PostgreSQL.Where("user_id = ?", userID).First(&deletion).Error; err != nil {
+		return nil, err
+	}
+	return &deletion, nil
+}
+
+// CancelDeletion 撤销注销申请
+func CancelDeletion(userID int64) (bool, error) {
+	result := dal.PostgreSQL.Where("user_id = ?", userID).Delete(&model.AccountDeletion{})
+	return result.RowsAffected > 0, result.Error
+}
+
+func AccountDeletionTask() {
+	ticker := time.NewTicker(1 * time.Minute)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		deleteScheduledAccounts()
+	}
+}
+
+func deleteScheduledAccounts() {
+	var deletions []model.AccountDeletion
+	err := dal.PostgreSQL.Where("scheduled_at < ?", time.Now()).Preload("User").Find(&deletions).Error
+	if err != nil {
+		log.Printf("查询待注销账号失败: %s", err)
+		return
+	}
+
+	for i := range deletions {
+		if err := deleteAccount(&deletions[i].User); err != nil {
+			log.Printf("注销账号失败(用户 %d): %s", deletions[i].UserID, err)
+			continue
+		}
+		log.Printf("已注销账号 %d", deletions[i].UserID)
+	}
+}
+
+// deleteAccount 删除用户的文章及其修订历史、表情回应、点赞收藏、评论、导出文件、头像以及账号本身，关联数据随外键级联删除
+func deleteAccount(user *model.User) error {
+	articlesDB, err := database.UseArticleData()
+	if err != nil {
+		return err
+	}
+	defer articlesDB.Close()
+
+	reactionsDB, err := database.UseReactionData()
+	if err != nil {
+		return err
+	}
+	defer reactionsDB.Close()
+
+	engagementDB, err := database.UseEngagementData()
+	if err != nil {
+		return err
+	}
+	defer engagementDB.Close()
+
+	commentsDB, err := database.UseCommentData()
+	if err != nil {
+		return err
+	}
+	defer commentsDB.Close()
+
+	// 先清理用户文章的关联数据，再撤销用户在其他文章上的回应、点赞收藏和评论
+	articles, err := articlesDB.ListArticlesByAuthor(user.Username)
+	if err != nil {
+		return err
+	}
+	ids := make([]int64, 0, len(articles))
+	for _, article := range articles {
+		ids = append(ids, article.ID)
+	}
+	if err := database.DeleteArticlesData(ids); err != nil {
+		return err
+	}
+	if err := reactionsDB.DeleteUserReactions(user.UserID); err != nil {
+		return err
+	}
+	if err := engagementDB.DeleteUserEngagement(user.UserID); err != nil {
+		return err
+	}
+	if err := commentsDB.DeleteUserComments(user.UserID); err != nil {
+		return err
+	}
+
+	if _, err := articlesDB.DeleteArticlesByAuthor(user.Username); err != nil {
+		return err
+	}
+
+	var exports []model.DataExport
+	if err := dal.PostgreSQL.Where("user_id = ?", user.UserID).Find(&exports).Error; err != nil {
+		return err
+	}
+	for i := range exports {
+		if exports[i].FilePath != "" {
+			os.Remove(exports[i].FilePath)
+		}
+	}
+
+	// 头像文件删除失败时保留账号，下次任务重试，避免留下无主的头像
+	if user.AvatarVersion != "" {
+		if err := avatar.Remove(context.Background(), avatar.OwnerUser, user.UserID, user.AvatarVersion); err != nil {
+			return err
+		}
+	}
+
+	if err := dal.PostgreSQL.Delete(user).Error; err != nil {
+		return err
+	}
+
+	audit.Record(audit.Entry{
+		ActorID:    &user.UserID,
+		Action:     audit.ActionAccountDelete,
+		TargetType: audit.TargetUser,
+		TargetID:   strconv.FormatInt(user.UserID, 10),
+	})
+	return nil
+}
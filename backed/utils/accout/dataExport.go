@@ -0,0 +1,298 @@
+package accout
+
+import (
+	"Backed/config"
+	"Backed/database"
+	"Backed/database/dal"
+	"Backed/database/model"
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	// staleExportTimeout 处理中的导出任务超过这个时间没有心跳，视为处理进程已退出
+	staleExportTimeout = 30 * time.Minute
+	// exportHeartbeat 生成导出期间刷新 started_at 的间隔，必须远小于 staleExportTimeout
+	exportHeartbeat = time.Minute
+)
+
+var accountCfg = config.AccountConfig{
+	DeletionGraceDays: 7,
+	ExportDir:         "data/exports",
+	ExportTTLHours:    72,
+}
+
+// InitAccountConfig 设置导出与注销相关的配置，未填写的项使用默认值
+func InitAccountConfig(cfg config.AccountConfig) {
+	if cfg.DeletionGraceDays > 0 {
+		accountCfg.DeletionGraceDays = cfg.DeletionGraceDays
+	}
+	if cfg.ExportDir != "" {
+		accountCfg.ExportDir = cfg.ExportDir
+	}
+	if cfg.ExportTTLHours > 0 {
+		accountCfg.ExportTTLHours = cfg.ExportTTLHours
+	}
+}
+
+type exportProfile struct {
+	UserID    int64      `json:"user_id,string"`
+	Username  string     `json:"username"`
+	Email     string     `json:"email"`
+	CreatedAt time.Time  `json:"created_at"`
+	LastLogin *time.Time `json:"last_login"`
+}
+
+type exportArchive struct {
+	ExportedAt time.Time               `json:"exported_at"`
+	Profile    exportProfile           `json:"profile"`
+	Blocks     []model.UserBlock       `json:"blocks"`
+	Reports    []model.Report          `json:"reports"`
+	Calls      []model.CallRecord      `json:"calls"`
+	Articles   []*database.Article     `json:"articles"`
+	Comments   []*database.Comment     `json:"comments"`
+	Likes      []database.UserArticle  `json:"likes"`
+	Bookmarks  []database.UserArticle  `json:"bookmarks"`
+	Reactions  []database.UserReaction `json:"reactions"`
+}
+
+func DataExportTask() {
+	ticker := time.NewTicker(1 * time.Minute)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		failStaleExports()
+		processPendingExports()
+		cleanupExpiredExports()
+	}
+}
+
+func processPendingExports() {
+	var exports []model.DataExport
+	err := dal.PostgreSQL.Where("status = ?", model.ExportPending).Order("id ASC").Limit(10).Find(&exports).Error
+	if err != nil {
+		log.Printf("查询数据导出任务失败: %s", err)
+		return
+	}
+
+	for i := range exports {
+		export := &exports[i]
+
+		// 抢占任务，避免多个实例重复处理
+		result := dal.PostgreSQL.Model(&model.DataExport{}).
+			Where("id = ? AND status = ?", export.ID, model.ExportPending).
+			Updates(map[string]interface{}{"status": model.ExportProcessing, "started_at": time.Now()})
+		if result.Error != nil || result.RowsAffected == 0 {
+			continue
+		}
+
+		stop := make(chan struct{})
+		go exportHeartbeatTask(export.ID, stop)
+		path, err := buildExport(export)
+		close(stop)
+
+		now := time.Now()
+		updates := map[string]interface{}{"finished_at": now}
+		if err != nil {
+			log.Printf("生成数据导出失败(任务 %d): %s", export.ID, err)
+			updates["status"] = model.ExportFailed
+			updates["error"] = "生成导出文件失败"
+		} else {
+			updates["status"] = model.ExportDone
+			updates["file_path"] = path
+			updates["expires_at"] = now.Add(time.Duration(accountCfg.ExportTTLHours) * time.Hour)
+		}
+
+		// 任务已被判定超时时不再覆盖结果，用户可能已经重新申请
+		result = dal.PostgreSQL.Model(&model.DataExport{}).
+			Where("id = ? AND status = ?", export.ID, model.ExportProcessing).
+			Updates(updates)
+		if result.Error != nil {
+			log.Printf("更新数据导出任务失败(任务 %d): %s", export.ID, result.Error)
+			continue
+		}
+		if result.RowsAffected == 0 && err == nil {
+			log.Printf("数据导出任务 %d 已被标记为超时，丢弃生成的文件", export.ID)
+			os.Remove(path)
+		}
+	}
+}
+
+// exportHeartbeatTask 定期刷新处理中任务的 started_at，避免耗时较长的导出被误判为超时
+func exportHeartbeatTask(exportID int64, stop <-chan struct{}) {
+	ticker := time.NewTicker(exportHeartbeat)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			err := dal.PostgreSQL.Model(&model.DataExport{}).
+				Where("id = ? AND status = ?", exportID, model.ExportProcessing).
+				Update("started_at", time.Now()).Error
+			if err != nil {
+				log.Printf("刷新数据导出任务心跳失败(任务 %d): %s", exportID, err)
+			}
+		}
+	}
+}
+
+// failStaleExports 进程在生成过程中退出时任务会停留在处理中，心跳超时后标记为失败，用户可以重新申请
+func failStaleExports() {
+	result := dal.PostgreSQL.Model(&model.DataExport{}).
+		Where("status = ? AND (started_at IS NULL OR started_at < ?)", model.ExportProcessing, time.Now().Add(-staleExportTimeout)).
+		Updates(map[string]interface{}{
+			"status":      model.ExportFailed,
+			"error":       "导出任务超时，请重新申请",
+			"finished_at": time.Now(),
+		})
+	if result.Error != nil {
+		log.Printf("处理超时的数据导出任务失败: %s", result.Error)
+		return
+	}
+	if result.RowsAffected > 0 {
+		log.Printf("已将 %d 个超时的数据导出任务标记为失败", result.RowsAffected)
+	}
+}
+
+// buildExport 收集用户数据并打包为ZIP，返回文件路径
+func buildExport(export *model.DataExport) (string, error) {
+	var user model.User
+	if err := dal.PostgreSQL.Where("user_id = ?", export.UserID).First(&user).Error; err != nil {
+		return "", err
+	}
+
+	archive := exportArchive{
+		ExportedAt: time.Now(),
+		Profile: exportProfile{
+			UserID:    user.UserID,
+			Username:  user.Username,
+			Email:     user.Email,
+			CreatedAt: user.CreatedAt,
+			LastLogin: user.LastLogin,
+		},
+	}
+
+	if err := dal.PostgreSQL.Where("blocker_id = ?", user.UserID).Find(&archive.Blocks).Error; err != nil {
+		return "", err
+	}
+	if err := dal.PostgreSQL.Where("reporter_id = ?", user.UserID).Find(&archive.Reports).Error; err != nil {
+		return "", err
+	}
+	err := dal.PostgreSQL.Where("caller_id = ? OR callee_id = ?", user.UserID, user.UserID).Find(&archive.Calls).Error
+	if err != nil {
+		return "", err
+	}
+
+	articlesDB, err := database.UseArticleData()
+	if err != nil {
+		return "", err
+	}
+	defer articlesDB.Close()
+
+	archive.Articles, err = articlesDB.ListArticlesByAuthor(user.Username)
+	if err != nil {
+		return "", err
+	}
+	// 列表查询不含正文，导出时逐篇补全
+	for i, article := range archive.Articles {
+		full, err := articlesDB.GetArticleByID(article.ID)
+		if err != nil {
+			return "", err
+		}
+		archive.Articles[i] = full
+	}
+
+	commentsDB, err := database.UseCommentData()
+	if err != nil {
+		return "", err
+	}
+	defer commentsDB.Close()
+
+	archive.Comments, err = commentsDB.ListUserComments(user.UserID)
+	if err != nil {
+		return "", err
+	}
+
+	engagementDB, err := database.UseEngagementData()
+	if err != nil {
+		return "", err
+	}
+	defer engagementDB.Close()
+
+	if archive.Likes, err = engagementDB.ListUserLikes(user.UserID); err != nil {
+		return "", err
+	}
+	if archive.Bookmarks, err = engagementDB.ListUserBookmarks(user.UserID); err != nil {
+		return "", err
+	}
+
+	reactionsDB, err := database.UseReactionData()
+	if err != nil {
+		return "", err
+	}
+	defer reactionsDB.Close()
+
+	if archive.Reactions, err = reactionsDB.ListReactionsByUser(user.UserID); err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(accountCfg.ExportDir, 0o700); err != nil {
+		return "", err
+	}
+	path := filepath.Join(accountCfg.ExportDir, fmt.Sprintf("export-%d-%d.zip", user.UserID, export.ID))
+
+	if err := writeExportZip(path, &archive); err != nil {
+		os.Remove(path)
+		return "", err
+	}
+	return path, nil
+}
+
+func writeExportZip(path string, archive *exportArchive) error {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	zw := zip.NewWriter(file)
+	w, err := zw.Create("data.json")
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(archive); err != nil {
+		return err
+	}
+
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+func cleanupExpiredExports() {
+	var exports []model.DataExport
+	err := dal.PostgreSQL.Where("status = ? AND expires_at < ?", model.ExportDone, time.Now()).Find(&exports).Error
+	if err != nil {
+		log.Printf("查询过期数据导出失败: %s", err)
+		return
+	}
+
+	for i := range exports {
+		if err := os.Remove(exports[i].FilePath); err != nil && !os.IsNotExist(err) {
+			log.Printf("删除导出文件失败: %s", err)
+			continue
+		}
+		dal.PostgreSQL.Delete(&exports[i])
+	}
+}
@@ -0,0 +1,193 @@
+package attachment
+
+import (
+	"Backed/config"
+	"Backed/database/dal"
+	"Backed/database/model"
+	"Backed/utils/storage"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"strings"
+
+	"gorm.io/gorm/clause"
+)
+
+const mb = 1 << 20
+
+var (
+	ErrInvalidKind    = errors.New("不支持的附件类型")
+	ErrTooLarge       = errors.New("文件过大")
+	ErrTypeNotAllowed = errors.New("不支持的文件格式")
+)
+
+// 各类型附件的大小上限
+var maxSize = map[string]int64{
+	model.AttachmentImage: 10 * mb,
+	model.AttachmentFile:  100 * mb,
+	model.AttachmentVoice: 20 * mb,
+}
+
+// 各类型附件允许的格式，以 / 结尾的表示前缀匹配，为空表示不限制
+var allowedTypes = map[string][]string{
+	model.AttachmentImage: {"image/jpeg", "image/png", "image/gif", "image/webp"},
+	model.AttachmentVoice: {"audio/", "application/ogg", "video/webm"},
+	model.AttachmentFile:  nil,
+}
+
+// audioBrands 只包含音频的 MP4 容器品牌
+var audioBrands = map[string]bool{"M4A ": true, "M4B ": true}
+
+// Init 从配置读取大小上限
+func Init(cfg config.StorageConfig) {
+	if cfg.ImageMaxMB > 0 {
+		maxSize[model.AttachmentImage] = int64(cfg.ImageMaxMB) * mb
+	}
+	if cfg.FileMaxMB > 0 {
+		maxSize[model.AttachmentFile] = int64(cfg.FileMaxMB) * mb
+	}
+	if cfg.VoiceMaxMB > 0 {
+		maxSize[model.AttachmentVoice] = int64(cfg.VoiceMaxMB) * mb
+	}
+}
+
+// Save 校验并保存上传的文件，内容相同的文件只会存储一份
+func Save(ctx context.Context, uploaderID int64, kind, fileName string, r io.Reader) (*model.Attachment, error) {
+	limit, ok := maxSize[kind]
+	if !ok {
+		return nil, ErrInvalidKind
+	}
+
+	// 先写入临时文件，同时计算哈希
+	tmp, err := os.CreateTemp("", "attachment-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	hasher := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, hasher), io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if size > limit {
+		return nil, ErrTooLarge
+	}
+
+	contentType, err := sniffContentType(tmp)
+	if err != nil {
+		return nil, err
+	}
+	if !typeAllowed(kind, contentType) {
+		return nil, ErrTypeNotAllowed
+	}
+
+	hash := hex.EncodeToString(hasher.Sum(nil))
+	if err := ensureBlob(ctx, hash, size, contentType, tmp); err != nil {
+		return nil, err
+	}
+
+	attachment := model.Attachment{
+		UploaderID: uploaderID,
+		BlobHash:   hash,
+		Kind:       kind,
+		FileName:   cleanFileName(fileName),
+	}
+	if err := dal.PostgreSQL.Create(&attachment).Error; err != nil {
+		return nil, err
+	}
+	return &attachment, nil
+}
+
+// ensureBlob 文件不存在时写入存储后端并登记
+func ensureBlob(ctx context.Context, hash string, size int64, contentType string, file *os.File) error {
+	var count int64
+	if err := dal.PostgreSQL.Model(&model.AttachmentBlob{}).Where("hash = ?", hash).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	key := storage.HashKey(hash)
+	if err := storage.Default.Put(ctx, key, file, size, contentType); err != nil {
+		return err
+	}
+
+	blob := model.AttachmentBlob{
+		Hash:        hash,
+		Size:        size,
+		ContentType: contentType,
+		StorageKey:  key,
+	}
+	return dal.PostgreSQL.Clauses(clause.OnConflict{DoNothing: true}).Create(&blob).Error
+}
+
+// Open 打开附件内容
+func Open(ctx context.Context, attachment *model.Attachment) (io.ReadCloser, *model.AttachmentBlob, error) {
+	var blob model.AttachmentBlob
+	if err := dal.PostgreSQL.Where("hash = ?", attachment.BlobHash).First(&blob).Error; err != nil {
+		return nil, nil, err
+	}
+	reader, err := storage.Default.Get(ctx, blob.StorageKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	return reader, &blob, nil
+}
+
+// sniffContentType 根据文件内容判断格式，不信任客户端声明的类型
+func sniffContentType(file *os.File) (string, error) {
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	head := make([]byte, 512)
+	n, err := io.ReadFull(file, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return "", err
+	}
+
+	contentType, _, err := mime.ParseMediaType(http.DetectContentType(head[:n]))
+	if err != nil {
+		return "application/octet-stream", nil
+	}
+	// DetectContentType 将所有 MP4 容器识别为视频，AAC 语音（m4a）需要根据 ftyp 的品牌区分
+	if contentType == "video/mp4" && n >= 12 && audioBrands[string(head[8:12])] {
+		return "audio/mp4", nil
+	}
+	return contentType, nil
+}
+
+func typeAllowed(kind, contentType string) bool {
+	allowed := allowedTypes[kind]
+	if allowed == nil {
+		return true
+	}
+	for _, t := range allowed {
+		if t == contentType || (strings.HasSuffix(t, "/") && strings.HasPrefix(contentType, t)) {
+			return true
+		}
+	}
+	return false
+}
+
+// cleanFileName 去掉路径部分并限制长度
+func cleanFileName(name string) string {
+	if i := strings.LastIndexAny(name, "/\\"); i >= 0 {
+		name = name[i+1:]
+	}
+	runes := []rune(name)
+	if len(runes) > 255 {
+		runes = runes[len(runes)-255:]
+	}
+	return string(runes)
+}
@@ -0,0 +1,206 @@
+package attachment
+
+import (
+	"Backed/database/dal"
+	"Backed/database/dal/daltest"
+	"Backed/database/model"
+	"Backed/utils/storage"
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+)
+
+var pngData = append([]byte("\x89PNG\r\n\x1a\n"), bytes.Repeat([]byte{0}, 64)...)
+
+// setup 准备测试数据库和用户 1，本地目录作为存储后端
+func setup(t *testing.T) *storage.LocalStorage {
+	t.Helper()
+
+	db := daltest.SQLite(t, &model.User{}, &model.AttachmentBlob{}, &model.Attachment{})
+	if err := db.Create(&model.User{UserID: 1, Username: "alice", Email: "alice@example.com"}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	local, err := storage.NewLocalStorage(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	old := storage.Default
+	storage.Default = local
+	t.Cleanup(func() { storage.Default = old })
+	return local
+}
+
+func TestSaveDeduplicatesByHash(t *testing.T) {
+	local := setup(t)
+	ctx := t.Context()
+
+	first, err := Save(ctx, 1, model.AttachmentImage, "a.png", bytes.NewReader(pngData))
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := Save(ctx, 1, model.AttachmentImage, "dir/b.png", bytes.NewReader(pngData))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if first.ID == second.ID {
+		t.Fatal("每次上传应产生独立的附件记录")
+	}
+	if first.BlobHash != second.BlobHash {
+		t.Fatalf("相同内容的哈希不一致: %s / %s", first.BlobHash, second.BlobHash)
+	}
+	if second.FileName != "b.png" {
+		t.Fatalf("文件名未去掉路径: %s", second.FileName)
+	}
+
+	var blobs []model.AttachmentBlob
+	if err := dal.PostgreSQL.Find(&blobs).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(blobs) != 1 {
+		t.Fatalf("相同内容应只存储一份，实际 %d 份", len(blobs))
+	}
+	if blobs[0].ContentType != "image/png" || blobs[0].Size != int64(len(pngData)) {
+		t.Fatalf("文件信息错误: %+v", blobs[0])
+	}
+	if exists, err := local.Exists(ctx, blobs[0].StorageKey); err != nil || !exists {
+		t.Fatalf("存储后端中缺少文件: %v", err)
+	}
+
+	reader, blob, err := Open(ctx, second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+	var buf bytes.Buffer
+	if _, err := buf.ReadFrom(reader); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), pngData) || blob.Hash != second.BlobHash {
+		t.Fatal("读取的内容与上传的不一致")
+	}
+}
+
+func TestSaveRejectsSpoofedType(t *testing.T) {
+	setup(t)
+
+	// 扩展名声称是图片，内容实际是 HTML
+	html := []byte("<!DOCTYPE html><html><script>alert(1)</script></html>")
+	_, err := Save(t.Context(), 1, model.AttachmentImage, "photo.png", bytes.NewReader(html))
+	if !errors.Is(err, ErrTypeNotAllowed) {
+		t.Fatalf("伪装的图片应被拒绝，实际 %v", err)
+	}
+
+	var count int64
+	dal.PostgreSQL.Model(&model.AttachmentBlob{}).Count(&count)
+	if count != 0 {
+		t.Fatalf("被拒绝的文件不应写入，实际 %d 份", count)
+	}
+
+	// 不限制格式的普通文件可以上传，类型以嗅探结果为准
+	attachment, err := Save(t.Context(), 1, model.AttachmentFile, "photo.png", bytes.NewReader(html))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var blob model.AttachmentBlob
+	dal.PostgreSQL.Where("hash = ?", attachment.BlobHash).First(&blob)
+	if blob.ContentType != "text/html" {
+		t.Fatalf("类型应来自内容嗅探，实际 %s", blob.ContentType)
+	}
+}
+
+// mp4Header 生成 MP4 容器的 ftyp 头，brand 为主品牌
+func mp4Header(brand string) []byte {
+	box := append([]byte("\x00\x00\x00\x1cftyp"+brand+"\x00\x00\x00\x00"), []byte(brand+"mp42isom")...)
+	return append(box, bytes.Repeat([]byte{0}, 64)...)
+}
+
+func TestSaveVoiceAcceptsM4A(t *testing.T) {
+	setup(t)
+
+	attachment, err := Save(t.Context(), 1, model.AttachmentVoice, "voice.m4a", bytes.NewReader(mp4Header("M4A ")))
+	if err != nil {
+		t.Fatalf("m4a 语音应被接受，实际 %v", err)
+	}
+	var blob model.AttachmentBlob
+	dal.PostgreSQL.Where("hash = ?", attachment.BlobHash).First(&blob)
+	if blob.ContentType != "audio/mp4" {
+		t.Fatalf("m4a 应识别为 audio/mp4，实际 %s", blob.ContentType)
+	}
+
+	// 普通 MP4 视频不能作为语音上传
+	_, err = Save(t.Context(), 1, model.AttachmentVoice, "clip.m4a", bytes.NewReader(mp4Header("isom")))
+	if !errors.Is(err, ErrTypeNotAllowed) {
+		t.Fatalf("MP4 视频应被拒绝，实际 %v", err)
+	}
+}
+
+func TestSaveRejectsOversized(t *testing.T) {
+	setup(t)
+
+	old := maxSize[model.AttachmentVoice]
+	maxSize[model.AttachmentVoice] = 16
+	t.Cleanup(func() { maxSize[model.AttachmentVoice] = old })
+
+	_, err := Save(t.Context(), 1, model.AttachmentVoice, "a.ogg", bytes.NewReader(make([]byte, 17)))
+	if !errors.Is(err, ErrTooLarge) {
+		t.Fatalf("超出大小上限应被拒绝，实际 %v", err)
+	}
+	if _, err := Save(t.Context(), 1, "video", "a.mp4", bytes.NewReader(pngData)); !errors.Is(err, ErrInvalidKind) {
+		t.Fatalf("未知的附件类型应被拒绝，实际 %v", err)
+	}
+}
+
+func TestCleanupOrphanBlobs(t *testing.T) {
+	local := setup(t)
+	ctx := t.Context()
+
+	orphan, err := Save(ctx, 1, model.AttachmentImage, "orphan.png", bytes.NewReader(pngData))
+	if err != nil {
+		t.Fatal(err)
+	}
+	kept, err := Save(ctx, 1, model.AttachmentFile, "kept.txt", bytes.NewReader([]byte("hello")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fresh, err := Save(ctx, 1, model.AttachmentFile, "fresh.txt", bytes.NewReader([]byte("just uploaded")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// orphan 与 fresh 失去引用，只有 orphan 超过了一小时的保护期
+	dal.PostgreSQL.Delete(orphan)
+	dal.PostgreSQL.Delete(fresh)
+	old := time.Now().Add(-2 * time.Hour)
+	dal.PostgreSQL.Model(&model.AttachmentBlob{}).
+		Where("hash IN ?", []string{orphan.BlobHash, kept.BlobHash}).
+		Update("created_at", old)
+
+	cleanupOrphanBlobs()
+
+	remaining := map[string]bool{}
+	var blobs []model.AttachmentBlob
+	dal.PostgreSQL.Find(&blobs)
+	for _, blob := range blobs {
+		remaining[blob.Hash] = true
+	}
+	if remaining[orphan.BlobHash] {
+		t.Fatal("无引用的文件未被清理")
+	}
+	if !remaining[kept.BlobHash] {
+		t.Fatal("仍被引用的文件不应被清理")
+	}
+	if !remaining[fresh.BlobHash] {
+		t.Fatal("保护期内的文件不应被清理")
+	}
+
+	if exists, _ := local.Exists(ctx, storage.HashKey(orphan.BlobHash)); exists {
+		t.Fatal("存储后端中的无引用文件未被删除")
+	}
+	if exists, _ := local.Exists(ctx, storage.HashKey(kept.BlobHash)); !exists {
+		t.Fatal("存储后端中仍被引用的文件被误删")
+	}
+}
@@ -0,0 +1,43 @@
+package attachment
+
+import (
+	"Backed/database/dal"
+	"Backed/database/model"
+	"Backed/utils/storage"
+	"context"
+	"log"
+	"time"
+)
+
+func CleanupOrphanBlobsTask() {
+	ticker := time.NewTicker(10 * time.Minute)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		cleanupOrphanBlobs()
+	}
+}
+
+// cleanupOrphanBlobs 删除没有任何附件引用的文件，留出一小时避免与正在进行的上传冲突
+func cleanupOrphanBlobs() {
+	var blobs []model.AttachmentBlob
+	err := dal.PostgreSQL.
+		Where("created_at < ?", time.Now().Add(-1*time.Hour)).
+		Where("NOT EXISTS (SELECT 1 FROM attachments WHERE attachments.blob_hash = attachment_blobs.hash)").
+		Limit(500).
+		Find(&blobs).Error
+	if err != nil {
+		log.Printf("查询无引用附件失败: %s", err)
+		return
+	}
+
+	for i := range blobs {
+		// 先删除记录，外键约束保证期间新增的引用不会被误删
+		if err := dal.PostgreSQL.Delete(&blobs[i]).Error; err != nil {
+			continue
+		}
+		if err := storage.Default.Delete(context.Background(), blobs[i].StorageKey); err != nil {
+			log.Printf("删除附件文件失败: %s", err)
+		}
+	}
+}
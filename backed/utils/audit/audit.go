@@ -0,0 +1,161 @@
+package audit
+
+import (
+	"Backed/database/dal"
+	"Backed/database/model"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 审计动作
+const (
+	ActionLogin                = "auth.login"
+	ActionLoginFailed          = "auth.login_failed"
+	ActionUserBan              = "admin.user_ban"
+	ActionUserUnban            = "admin.user_unban"
+	ActionForceLogout          = "admin.force_logout"
+	ActionReportHandle         = "admin.report_handle"
+	ActionArticleDelete        = "article.delete"
+	ActionArticleEdit          = "article.edit"
+	ActionCommentDelete        = "comment.delete"
+	ActionAccountDelete        = "account.delete"
+	ActionAccountDeleteRequest = "account.delete_request"
+	ActionAccountDeleteCancel  = "account.delete_cancel"
+)
+
+// 目标类型
+const (
+	TargetUser    = "user"
+	TargetArticle = "article"
+	TargetReport  = "report"
+	TargetComment = "comment"
+)
+
+// 追加审计日志时使用的事务级咨询锁，保证哈希链串行写入
+const chainLockKey = 0x626c6f636b696d
+
+// genesisHash 哈希链的起点
+var genesisHash = fmt.Sprintf("%064d", 0)
+
+// Entry 一条待写入的审计事件
+type Entry struct {
+	ActorID    *int64
+	Action     string
+	TargetType string
+	TargetID   string
+	IP         string
+	Detail     string
+}
+
+// Record 追加一条审计日志，失败只记录到标准日志，不影响业务流程
+func Record(entry Entry) {
+	if err := Append(entry); err != nil {
+		log.Printf("写入审计日志失败: %s", err)
+	}
+}
+
+// RecordFromContext 使用请求的客户端IP记录审计日志
+func RecordFromContext(c *gin.Context, actorID *int64, action, targetType, targetID, detail string) {
+	Record(Entry{
+		ActorID:    actorID,
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   targetID,
+		IP:         c.ClientIP(),
+		Detail:     detail,
+	})
+}
+
+// Append 在事务中读取链尾并写入新记录
+func Append(entry Entry) error {
+	return dal.PostgreSQL.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", chainLockKey).Error; err != nil {
+			return err
+		}
+
+		prevHash := genesisHash
+		var last model.AuditLog
+		err := tx.Order("id DESC").Limit(1).Find(&last).Error
+		if err != nil {
+			return err
+		}
+		if last.ID != 0 {
+			prevHash = last.Hash
+		}
+
+		record := model.AuditLog{
+			ActorID:    entry.ActorID,
+			Action:     entry.Action,
+			TargetType: entry.TargetType,
+			TargetID:   entry.TargetID,
+			IP:         entry.IP,
+			Detail:     entry.Detail,
+			CreatedAt:  time.Now().UTC().Truncate(time.Microsecond),
+			PrevHash:   prevHash,
+		}
+		record.Hash = ComputeHash(&record)
+
+		return tx.Create(&record).Error
+	})
+}
+
+// ComputeHash 计算一条记录的哈希，ID 由数据库生成因此不参与计算
+func ComputeHash(r *model.AuditLog) string {
+	actor := ""
+	if r.ActorID != nil {
+		actor = strconv.FormatInt(*r.ActorID, 10)
+	}
+
+	h := sha256.New()
+	for _, field := range []string{
+		r.PrevHash,
+		r.CreatedAt.UTC().Format(time.RFC3339Nano),
+		actor,
+		r.Action,
+		r.TargetType,
+		r.TargetID,
+		r.IP,
+		r.Detail,
+	} {
+		// 写入长度前缀，避免字段拼接产生歧义
+		h.Write([]byte(strconv.Itoa(len(field))))
+		h.Write([]byte{':'})
+		h.Write([]byte(field))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Verify 从头校验整条哈希链，返回第一条异常记录的ID，0 表示链完整
+func Verify() (int64, error) {
+	const batchSize = 500
+
+	prevHash := genesisHash
+	var lastID int64
+	for {
+		var batch []model.AuditLog
+		err := dal.PostgreSQL.Where("id > ?", lastID).Order("id ASC").Limit(batchSize).Find(&batch).Error
+		if err != nil {
+			return 0, err
+		}
+
+		for i := range batch {
+			r := &batch[i]
+			if r.PrevHash != prevHash || ComputeHash(r) != r.Hash {
+				return r.ID, nil
+			}
+			prevHash = r.Hash
+			lastID = r.ID
+		}
+
+		if len(batch) < batchSize {
+			return 0, nil
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package avatar
+
+import (
+	"Backed/utils/storage"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"io"
+
+	_ "image/gif" // 注册GIF解码器
+	_ "image/png" // 注册PNG解码器
+)
+
+const (
+	MaxUploadSize = 10 << 20 // 上传大小上限
+	maxPixels     = 40_000_000
+	jpegQuality   = 88
+	DefaultSize   = 256
+
+	OwnerUser = "user"
+)
+
+// Sizes 生成的头像尺寸，从小到大排列
+var Sizes = []int{64, 128, 256, 512}
+
+var (
+	ErrTooLarge     = errors.New("图片过大")
+	ErrInvalidImage = errors.New("无法识别的图片格式")
+)
+
+// Processed 处理后的头像，Version 为原图内容哈希的前缀
+type Processed struct {
+	Version string
+	Images  map[int][]byte
+}
+
+// Process 解码图片，按EXIF方向校正后裁剪为正方形并生成各尺寸的JPEG
+// 重新编码后原图中的EXIF等元数据不会被保留
+func Process(r io.Reader) (*Processed, error) {
+	data, err := io.ReadAll(io.LimitReader(r, MaxUploadSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > MaxUploadSize {
+		return nil, ErrTooLarge
+	}
+
+	// 先读取尺寸，防止解压炸弹
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrInvalidImage
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > maxPixels {
+		return nil, ErrTooLarge
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrInvalidImage
+	}
+
+	img := toRGBA(src)
+	if format == "jpeg" {
+		img = applyOrientation(img, jpegOrientation(data))
+	}
+	img = cropSquare(img)
+
+	sum := sha256.Sum256(data)
+	processed := &Processed{
+		Version: hex.EncodeToString(sum[:])[:16],
+		Images:  make(map[int][]byte, len(Sizes)),
+	}
+	for _, size := range Sizes {
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, resize(img, size), &jpeg.Options{Quality: jpegQuality}); err != nil {
+			return nil, err
+		}
+		processed.Images[size] = buf.Bytes()
+	}
+	return processed, nil
+}
+
+// Store 将各尺寸头像写入附件存储后端
+func Store(ctx context.Context, ownerType string, ownerID int64, p *Processed) error {
+	for size, data := range p.Images {
+		key := Key(ownerType, ownerID, p.Version, size)
+		if err := storage.Default.Put(ctx, key, bytes.NewReader(data), int64(len(data)), "image/jpeg"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Remove 删除某个版本的全部尺寸
+func Remove(ctx context.Context, ownerType string, ownerID int64, version string) error {
+	for _, size := range Sizes {
+		if err := storage.Default.Delete(ctx, Key(ownerType, ownerID, version, size)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Key 头像在存储后端中的对象键
+func Key(ownerType string, ownerID int64, version string, size int) string {
+	return fmt.Sprintf("avatars/%s/%d/%s_%d.jpg", ownerType, ownerID, version, size)
+}
+
+// URL 头像的固定访问地址，版本号用于刷新缓存
+func URL(ownerType string, ownerID int64, version string) string {
+	if version == "" {
+		return ""
+	}
+	return fmt.Sprintf("/avatar/%s/%d?v=%s", ownerType, ownerID, version)
+}
+
+// NearestSize 返回不小于请求尺寸的最小可用尺寸
+func NearestSize(requested int) int {
+	for _, size := range Sizes {
+		if size >= requested {
+			return size
+		}
+	}
+	return Sizes[len(Sizes)-1]
+}
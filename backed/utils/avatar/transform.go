@@ -0,0 +1,113 @@
+package avatar
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+)
+
+// toRGBA 将任意图片转换为RGBA，透明部分以白色填充
+func toRGBA(src image.Image) *image.RGBA {
+	bounds := src.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(dst, dst.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(dst, dst.Bounds(), src, bounds.Min, draw.Over)
+	return dst
+}
+
+// applyOrientation 按EXIF方向旋转或翻转图片
+func applyOrientation(src *image.RGBA, orientation int) *image.RGBA {
+	if orientation <= 1 || orientation > 8 {
+		return src
+	}
+
+	w, h := src.Bounds().Dx(), src.Bounds().Dy()
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2: // 水平翻转
+				dx, dy = w-1-x, y
+			case 3: // 旋转180度
+				dx, dy = w-1-x, h-1-y
+			case 4: // 垂直翻转
+				dx, dy = x, h-1-y
+			case 5: // 沿主对角线翻转
+				dx, dy = y, x
+			case 6: // 顺时针旋转90度
+				dx, dy = h-1-y, x
+			case 7: // 沿副对角线翻转
+				dx, dy = h-1-y, w-1-x
+			case 8: // 逆时针旋转90度
+				dx, dy = y, w-1-x
+			}
+			si := src.PixOffset(x, y)
+			di := dst.PixOffset(dx, dy)
+			copy(dst.Pix[di:di+4], src.Pix[si:si+4])
+		}
+	}
+	return dst
+}
+
+// cropSquare 居中裁剪为正方形
+func cropSquare(src *image.RGBA) *image.RGBA {
+	w, h := src.Bounds().Dx(), src.Bounds().Dy()
+	side := min(w, h)
+	x0, y0 := (w-side)/2, (h-side)/2
+
+	dst := image.NewRGBA(image.Rect(0, 0, side, side))
+	draw.Draw(dst, dst.Bounds(), src, image.Point{X: x0, Y: y0}, draw.Src)
+	return dst
+}
+
+// resize 使用区域平均缩放为 size x size，适合缩小；放大时退化为最近邻采样
+func resize(src *image.RGBA, size int) *image.RGBA {
+	sw, sh := src.Bounds().Dx(), src.Bounds().Dy()
+	dst := image.NewRGBA(image.Rect(0, 0, size, size))
+	scaleX := float64(sw) / float64(size)
+	scaleY := float64(sh) / float64(size)
+
+	for dy := 0; dy < size; dy++ {
+		y0 := float64(dy) * scaleY
+		y1 := y0 + scaleY
+		for dx := 0; dx < size; dx++ {
+			x0 := float64(dx) * scaleX
+			x1 := x0 + scaleX
+
+			var r, g, b, a, total float64
+			for sy := int(y0); sy < sh && float64(sy) < y1; sy++ {
+				wy := overlap(y0, y1, float64(sy))
+				for sx := int(x0); sx < sw && float64(sx) < x1; sx++ {
+					weight := wy * overlap(x0, x1, float64(sx))
+					i := src.PixOffset(sx, sy)
+					r += float64(src.Pix[i]) * weight
+					g += float64(src.Pix[i+1]) * weight
+					b += float64(src.Pix[i+2]) * weight
+					a += float64(src.Pix[i+3]) * weight
+					total += weight
+				}
+			}
+
+			i := dst.PixOffset(dx, dy)
+			if total == 0 {
+				continue
+			}
+			dst.Pix[i] = uint8(r/total + 0.5)
+			dst.Pix[i+1] = uint8(g/total + 0.5)
+			dst.Pix[i+2] = uint8(b/total + 0.5)
+			dst.Pix[i+3] = uint8(a/total + 0.5)
+		}
+	}
+	return dst
+}
+
+// overlap 计算源像素 [p, p+1) 与区间 [lo, hi) 的重叠长度
+func overlap(lo, hi, p float64) float64 {
+	return max(0, min(hi, p+1)-max(lo, p))
+}
@@ -0,0 +1,83 @@
+package call
+
+import (
+	"Backed/database/dal"
+	"Backed/database/model"
+	"Backed/utils/push"
+	"Backed/utils/realtime"
+	"log"
+	"strconv"
+)
+
+// missedOutcomes 对被叫而言算作未接来电的通话结果
+var missedOutcomes = []string{model.CallMissed, model.CallBusy}
+
+// notifyMissed 以系统消息的形式通知被叫有未接来电，并附带最新的未读数量
+func notifyMissed(record *model.CallRecord) {
+	var caller model.User
+	if err := dal.PostgreSQL.Select("user_id", "username").Where("user_id = ?", record.CallerID).First(&caller).Error; err != nil {
+		log.Printf("查询主叫信息失败: %s", err)
+		return
+	}
+
+	unread, err := UnreadMissedCount(record.CalleeID)
+	if err != nil {
+		log.Printf("统计未接来电失败: %s", err)
+		return
+	}
+
+	realtime.SendToUser(record.CalleeID, realtime.NewEvent("system.missed_call", realtime.H{
+		"call_id":     record.ID,
+		"caller_id":   strconv.FormatInt(caller.UserID, 10),
+		"caller_name": caller.Username,
+		"media":       record.Media,
+		"started_at":  record.StartedAt,
+		"unread":      unread,
+	}))
+
+	body := "未接语音通话"
+	if record.Media == model.CallVideo {
+		body = "未接视频通话"
+	}
+	push.Notify(record.CalleeID, push.Notification{
+		Kind:   push.KindMissedCall,
+		PeerID: caller.UserID,
+		Title:  caller.Username,
+		Body:   body,
+		Data:   map[string]string{"call_id": record.ID},
+	})
+}
+
+// sendUnreadSummary 连接建立时推送未读的未接来电数量，用于显示角标
+func sendUnreadSummary(client realtime.Client) {
+	unread, err := UnreadMissedCount(client.UserID())
+	if err != nil {
+		log.Printf("统计未接来电失败: %s", err)
+		return
+	}
+	if unread > 0 {
+		client.Send(realtime.NewEvent("call.unread", realtime.H{"unread": unread}))
+	}
+}
+
+// UnreadMissedCount 统计用户未查看的未接来电
+func UnreadMissedCount(userID int64) (int64, error) {
+	var count int64
+	err := dal.PostgreSQL.Model(&model.CallRecord{}).
+		Where("callee_id = ? AND outcome IN ? AND missed_read = ?", userID, missedOutcomes, false).
+		Count(&count).Error
+	return count, err
+}
+
+// MarkMissedRead 将用户的未接来电全部标记为已读，并同步到其他设备
+func MarkMissedRead(userID int64) error {
+	err := dal.PostgreSQL.Model(&model.CallRecord{}).
+		Where("callee_id = ? AND outcome IN ? AND missed_read = ?", userID, missedOutcomes, false).
+		Update("missed_read", true).Error
+	if err != nil {
+		return err
+	}
+
+	realtime.SendToUser(userID, realtime.NewEvent("call.unread", realtime.H{"unread": 0}))
+	return nil
+}
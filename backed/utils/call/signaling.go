@@ -0,0 +1,329 @@
+package call
+
+import (
+	"Backed/database/dal"
+	"Backed/database/model"
+	"Backed/utils/realtime"
+	"Backed/utils/relation"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ringTimeout 被叫超过该时间未接听视为未接来电
+const ringTimeout = 45 * time.Second
+
+// 通话结束的原因
+const (
+	reasonHangup       = "hangup"
+	reasonRejected     = "rejected"
+	reasonCancelled    = "cancelled"
+	reasonTimeout      = "timeout"
+	reasonDisconnected = "disconnected"
+)
+
+// session 进行中的通话，接听前被叫的所有设备都会响铃，接听后只与接听的设备交换信令
+type session struct {
+	id           string
+	callerID     int64
+	calleeID     int64
+	callerClient string
+	calleeClient string
+	media        string
+	startedAt    time.Time
+	answeredAt   *time.Time
+	timer        *time.Timer
+}
+
+func (s *session) peerOf(userID int64) (int64, string) {
+	if userID == s.callerID {
+		return s.calleeID, s.calleeClient
+	}
+	return s.callerID, s.callerClient
+}
+
+// participant 判断连接是否属于本次通话，接听前被叫的任意设备都可以操作
+func (s *session) participant(client realtime.Client) bool {
+	switch client.UserID() {
+	case s.callerID:
+		return client.ID() == s.callerClient
+	case s.calleeID:
+		return s.calleeClient == "" || client.ID() == s.calleeClient
+	}
+	return false
+}
+
+var (
+	mu       sync.Mutex
+	sessions = map[string]*session{}
+	busy     = map[int64]string{} // 用户ID -> 正在进行的通话ID
+)
+
+type signal struct {
+	CallID    string          `json:"call_id"`
+	CalleeID  int64           `json:"callee_id,string"`
+	Media     string          `json:"media"`
+	SDP       json.RawMessage `json:"sdp,omitempty"`
+	Candidate json.RawMessage `json:"candidate,omitempty"`
+}
+
+// Init 注册通话信令的处理函数
+func Init() {
+	realtime.Handle("call.invite", handleInvite)
+	realtime.Handle("call.ring", withSession(handleRing))
+	realtime.Handle("call.accept", withSession(handleAccept))
+	realtime.Handle("call.reject", withSession(func(client realtime.Client, s *session, _ *signal) {
+		if client.UserID() == s.calleeID && s.answeredAt == nil {
+			endLocked(s, model.CallRejected, reasonRejected)
+		}
+	}))
+	realtime.Handle("call.cancel", withSession(func(client realtime.Client, s *session, _ *signal) {
+		if client.UserID() == s.callerID && s.answeredAt == nil {
+			endLocked(s, model.CallMissed, reasonCancelled)
+		}
+	}))
+	realtime.Handle("call.hangup", withSession(func(client realtime.Client, s *session, _ *signal) {
+		hangupLocked(s, reasonHangup)
+	}))
+	realtime.Handle("call.sdp", withSession(func(client realtime.Client, s *session, sig *signal) {
+		relayLocked(client, s, "call.sdp", realtime.H{"call_id": s.id, "sdp": sig.SDP})
+	}))
+	realtime.Handle("call.ice", withSession(func(client realtime.Client, s *session, sig *signal) {
+		relayLocked(client, s, "call.ice", realtime.H{"call_id": s.id, "candidate": sig.Candidate})
+	}))
+	realtime.OnConnect(sendUnreadSummary)
+	realtime.OnDisconnect(handleDisconnect)
+}
+
+func handleInvite(client realtime.Client, data json.RawMessage) {
+	var sig signal
+	if err := json.Unmarshal(data, &sig); err != nil || sig.CalleeID == 0 {
+		sendError(client, "call.invite", "无效的通话请求")
+		return
+	}
+	if sig.Media != model.CallAudio && sig.Media != model.CallVideo {
+		sendError(client, "call.invite", "不支持的通话类型")
+		return
+	}
+
+	callerID := client.UserID()
+	if sig.CalleeID == callerID {
+		sendError(client, "call.invite", "不能呼叫自己")
+		return
+	}
+
+	var callee model.User
+	if err := dal.PostgreSQL.Select("user_id").Where("user_id = ?", sig.CalleeID).First(&callee).Error; err != nil {
+		sendError(client, "call.invite", "用户不存在")
+		return
+	}
+
+	// 存在拉黑关系时不告知具体原因
+	blocked, err := relation.HasBlockBetween(callerID, sig.CalleeID)
+	if err != nil || blocked {
+		sendError(client, "call.invite", "对方暂时无法接听")
+		return
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if _, ok := busy[callerID]; ok {
+		sendError(client, "call.invite", "你正在通话中")
+		return
+	}
+
+	now := time.Now()
+	s := &session{
+		id:           newCallID(),
+		callerID:     callerID,
+		calleeID:     sig.CalleeID,
+		callerClient: client.ID(),
+		media:        sig.Media,
+		startedAt:    now,
+	}
+	client.Send(realtime.NewEvent("call.created", realtime.H{"call_id": s.id}))
+
+	if _, ok := busy[sig.CalleeID]; ok {
+		saveRecord(s, model.CallBusy, now)
+		client.Send(realtime.NewEvent("call.busy", realtime.H{"call_id": s.id}))
+		return
+	}
+
+	incoming := realtime.NewEvent("call.incoming", realtime.H{
+		"call_id":   s.id,
+		"caller_id": strconv.FormatInt(callerID, 10),
+		"media":     s.media,
+	})
+	if realtime.SendToUser(sig.CalleeID, incoming) == 0 {
+		saveRecord(s, model.CallMissed, now)
+		client.Send(realtime.NewEvent("call.unavailable", realtime.H{"call_id": s.id}))
+		return
+	}
+
+	sessions[s.id] = s
+	busy[callerID] = s.id
+	busy[sig.CalleeID] = s.id
+	s.timer = time.AfterFunc(ringTimeout, func() {
+		mu.Lock()
+		defer mu.Unlock()
+		if current, ok := sessions[s.id]; ok && current.answeredAt == nil {
+			endLocked(current, model.CallMissed, reasonTimeout)
+		}
+	})
+}
+
+// handleRing 被叫设备开始响铃，通知主叫
+func handleRing(client realtime.Client, s *session, _ *signal) {
+	if client.UserID() == s.calleeID && s.answeredAt == nil {
+		realtime.SendToClient(s.callerID, s.callerClient, realtime.NewEvent("call.ringing", realtime.H{"call_id": s.id}))
+	}
+}
+
+func handleAccept(client realtime.Client, s *session, _ *signal) {
+	if client.UserID() != s.calleeID || s.answeredAt != nil {
+		return
+	}
+
+	now := time.Now()
+	s.answeredAt = &now
+	s.calleeClient = client.ID()
+	s.timer.Stop()
+
+	realtime.SendToClient(s.callerID, s.callerClient, realtime.NewEvent("call.accepted", realtime.H{"call_id": s.id}))
+
+	// 让被叫的其他设备停止响铃
+	answered := realtime.NewEvent("call.answered_elsewhere", realtime.H{"call_id": s.id})
+	realtime.SendToUserExcept(s.calleeID, client.ID(), answered)
+}
+
+// withSession 解析信令并在持有锁的情况下找到对应通话
+func withSession(fn func(client realtime.Client, s *session, sig *signal)) realtime.HandlerFunc {
+	return func(client realtime.Client, data json.RawMessage) {
+		var sig signal
+		if err := json.Unmarshal(data, &sig); err != nil || sig.CallID == "" {
+			sendError(client, "", "无效的通话信令")
+			return
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		s, ok := sessions[sig.CallID]
+		if !ok || !s.participant(client) {
+			client.Send(realtime.NewEvent("call.ended", realtime.H{"call_id": sig.CallID, "reason": "not_found"}))
+			return
+		}
+		fn(client, s, &sig)
+	}
+}
+
+// relayLocked 将 SDP 与 ICE 候选转发给对端设备，接听前主叫的描述会发给被叫的所有设备
+func relayLocked(client realtime.Client, s *session, eventType string, payload realtime.H) {
+	peerID, peerClient := s.peerOf(client.UserID())
+	event := realtime.NewEvent(eventType, payload)
+	if peerClient == "" {
+		realtime.SendToUser(peerID, event)
+		return
+	}
+	realtime.SendToClient(peerID, peerClient, event)
+}
+
+// hangupLocked 接通后挂断记为已完成，接听前挂断按取消或拒绝处理
+func hangupLocked(s *session, reason string) {
+	if s.answeredAt != nil {
+		endLocked(s, model.CallCompleted, reason)
+		return
+	}
+	endLocked(s, model.CallMissed, reason)
+}
+
+// endLocked 结束通话，通知双方所有设备并保存记录
+func endLocked(s *session, outcome, reason string) {
+	if s.timer != nil {
+		s.timer.Stop()
+	}
+	delete(sessions, s.id)
+	if busy[s.callerID] == s.id {
+		delete(busy, s.callerID)
+	}
+	if busy[s.calleeID] == s.id {
+		delete(busy, s.calleeID)
+	}
+
+	now := time.Now()
+	saveRecord(s, outcome, now)
+
+	payload := realtime.H{"call_id": s.id, "outcome": outcome, "reason": reason}
+	if s.answeredAt != nil {
+		payload["duration"] = int(now.Sub(*s.answeredAt).Seconds())
+	}
+	ended := realtime.NewEvent("call.ended", payload)
+	realtime.SendToUser(s.callerID, ended)
+	realtime.SendToUser(s.calleeID, ended)
+}
+
+// handleDisconnect 参与通话的设备断开时结束通话
+func handleDisconnect(userID int64, clientID string) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	callID, ok := busy[userID]
+	if !ok {
+		return
+	}
+	s := sessions[callID]
+	if s == nil {
+		return
+	}
+
+	switch {
+	case userID == s.callerID && clientID == s.callerClient:
+		hangupLocked(s, reasonDisconnected)
+	case userID == s.calleeID && clientID == s.calleeClient:
+		hangupLocked(s, reasonDisconnected)
+	case userID == s.calleeID && s.calleeClient == "" && !realtime.IsOnline(userID):
+		// 响铃中的被叫已没有在线设备
+		endLocked(s, model.CallMissed, reasonDisconnected)
+	}
+}
+
+// saveRecord 异步保存通话记录，避免在持有锁时等待数据库
+func saveRecord(s *session, outcome string, endedAt time.Time) {
+	record := model.CallRecord{
+		ID:         s.id,
+		CallerID:   s.callerID,
+		CalleeID:   s.calleeID,
+		Media:      s.media,
+		Outcome:    outcome,
+		StartedAt:  s.startedAt,
+		AnsweredAt: s.answeredAt,
+		EndedAt:    endedAt,
+	}
+	if s.answeredAt != nil {
+		record.Duration = int(endedAt.Sub(*s.answeredAt).Seconds())
+	}
+	go func() {
+		if err := dal.PostgreSQL.Create(&record).Error; err != nil {
+			log.Printf("保存通话记录失败: %s", err)
+			return
+		}
+		if record.IsMissedFor(record.CalleeID) {
+			notifyMissed(&record)
+		}
+	}()
+}
+
+func sendError(client realtime.Client, ref, message string) {
+	client.Send(realtime.NewEvent("error", realtime.H{"message": message, "ref": ref}))
+}
+
+func newCallID() string {
+	buf := make([]byte, 16)
+	rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
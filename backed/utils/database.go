@@ -118,6 +118,15 @@ func (d *Database) Query(query string, args ...interface{}) (*sql.Rows, error) {
 	return d.db.Query(query, args...)
 }
 
+// Begin 开启事务
+func (d *Database) Begin() (*sql.Tx, error) {
+	if d.db == nil {
+		return nil, fmt.Errorf("数据库未初始化")
+	}
+
+	return d.db.Begin()
+}
+
 // QueryRow 执行单行查询
 func (d *Database) QueryRow(query string, args ...interface{}) *sql.Row {
 	if d.db == nil {
@@ -0,0 +1,152 @@
+package diff
+
+import (
+	"strings"
+)
+
+// 差异片段类型
+const (
+	Equal  = "equal"
+	Insert = "insert"
+	Delete = "delete"
+)
+
+// maxEditDistance 超过这个编辑距离时不再逐行比较，直接视为整体替换，避免超大文本占用过多内存
+const maxEditDistance = 2000
+
+// Op 一段连续的相同类型的行
+type Op struct {
+	Type  string   `json:"type"`
+	Lines []string `json:"lines"`
+}
+
+// Lines 按行比较两段文本，使用 Myers 算法得到最短编辑脚本
+func Lines(a, b string) []Op {
+	return compare(splitLines(a), splitLines(b))
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.ReplaceAll(s, "\r\n", "\n"), "\n")
+}
+
+func compare(a, b []string) []Op {
+	// 去掉公共前后缀，通常只有一小部分内容被修改
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	var ops []Op
+	ops = appendOp(ops, Equal, a[:prefix]...)
+	ops = appendMiddle(ops, a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])
+	ops = appendOp(ops, Equal, a[len(a)-suffix:]...)
+	return ops
+}
+
+func appendMiddle(ops []Op, a, b []string) []Op {
+	n, m := len(a), len(b)
+	if n == 0 || m == 0 {
+		ops = appendOp(ops, Delete, a...)
+		return appendOp(ops, Insert, b...)
+	}
+
+	max := n + m
+	if max > maxEditDistance {
+		max = maxEditDistance
+	}
+	offset := max + 1
+	v := make([]int, 2*max+3)
+	var trace [][]int
+
+	found := false
+	for d := 0; d <= max && !found; d++ {
+		// 回溯第 d 步时只会用到对角线 -d-1 到 d+1 的值
+		trace = append(trace, append([]int(nil), v[offset-d-1:offset+d+2]...))
+
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				found = true
+				break
+			}
+		}
+	}
+	if !found {
+		ops = appendOp(ops, Delete, a...)
+		return appendOp(ops, Insert, b...)
+	}
+
+	// 从终点沿记录的路径回溯，得到倒序的编辑步骤
+	type step struct {
+		kind string
+		line string
+	}
+	var steps []step
+	x, y := n, m
+	for d := len(trace) - 1; d > 0; d-- {
+		prev := trace[d]
+		base := d + 1
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && prev[base+k-1] < prev[base+k+1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := prev[base+prevK]
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			x--
+			y--
+			steps = append(steps, step{Equal, a[x]})
+		}
+		if x == prevX {
+			y--
+			steps = append(steps, step{Insert, b[y]})
+		} else {
+			x--
+			steps = append(steps, step{Delete, a[x]})
+		}
+	}
+	for x > 0 && y > 0 {
+		x--
+		y--
+		steps = append(steps, step{Equal, a[x]})
+	}
+
+	for i := len(steps) - 1; i >= 0; i-- {
+		ops = appendOp(ops, steps[i].kind, steps[i].line)
+	}
+	return ops
+}
+
+// appendOp 与上一段类型相同时合并
+func appendOp(ops []Op, kind string, lines ...string) []Op {
+	if len(lines) == 0 {
+		return ops
+	}
+	if len(ops) > 0 && ops[len(ops)-1].Type == kind {
+		ops[len(ops)-1].Lines = append(ops[len(ops)-1].Lines, lines...)
+		return ops
+	}
+	return append(ops, Op{Type: kind, Lines: append([]string(nil), lines...)})
+}
@@ -0,0 +1,138 @@
+package diff
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// apply 从差异片段还原出比较前后的两段文本
+func apply(ops []Op) (a, b []string) {
+	for _, op := range ops {
+		switch op.Type {
+		case Equal:
+			a = append(a, op.Lines...)
+			b = append(b, op.Lines...)
+		case Delete:
+			a = append(a, op.Lines...)
+		case Insert:
+			b = append(b, op.Lines...)
+		}
+	}
+	return a, b
+}
+
+// edits 差异中插入和删除的行数
+func edits(ops []Op) int {
+	n := 0
+	for _, op := range ops {
+		if op.Type != Equal {
+			n += len(op.Lines)
+		}
+	}
+	return n
+}
+
+// shortestEdits 用最长公共子序列的动态规划计算最短编辑行数，作为对照
+func shortestEdits(a, b []string) int {
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	return len(a) + len(b) - 2*lcs[0][0]
+}
+
+func checkRoundTrip(t *testing.T, a, b string, ops []Op) {
+	t.Helper()
+	gotA, gotB := apply(ops)
+	if strings.Join(gotA, "\n") != strings.Join(splitLines(a), "\n") || len(gotA) != len(splitLines(a)) {
+		t.Fatalf("无法还原原文:\n%q\n%+v", a, ops)
+	}
+	if strings.Join(gotB, "\n") != strings.Join(splitLines(b), "\n") || len(gotB) != len(splitLines(b)) {
+		t.Fatalf("无法还原新文本:\n%q\n%+v", b, ops)
+	}
+	for i := 1; i < len(ops); i++ {
+		if ops[i].Type == ops[i-1].Type {
+			t.Fatalf("相邻的同类片段未合并: %+v", ops)
+		}
+	}
+}
+
+func TestLines(t *testing.T) {
+	cases := []struct {
+		name  string
+		a, b  string
+		edits int
+	}{
+		{"都为空", "", "", 0},
+		{"相同", "a\nb\nc", "a\nb\nc", 0},
+		{"新增全部", "", "a\nb", 2},
+		{"删除全部", "a\nb", "", 2},
+		{"修改中间一行", "a\nb\nc", "a\nx\nc", 2},
+		{"开头插入", "b\nc", "a\nb\nc", 1},
+		{"结尾删除", "a\nb\nc", "a\nb", 1},
+		{"交换两行", "a\nb", "b\na", 2},
+		{"Myers 论文示例", "a\nb\nc\na\nb\nb\na", "c\nb\na\nb\na\nc", 5},
+		{"重复行", "a\na\na", "a\na", 1},
+		{"换行符统一", "a\r\nb", "a\nb", 0},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			ops := Lines(c.a, c.b)
+			checkRoundTrip(t, c.a, c.b, ops)
+			if got := edits(ops); got != c.edits {
+				t.Fatalf("编辑行数 %d，期望最短 %d: %+v", got, c.edits, ops)
+			}
+		})
+	}
+}
+
+func TestLinesRandom(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	alphabet := []string{"a", "b", "c", "d"}
+	random := func() string {
+		lines := make([]string, rng.Intn(30))
+		for i := range lines {
+			lines[i] = alphabet[rng.Intn(len(alphabet))]
+		}
+		return strings.Join(lines, "\n")
+	}
+	for i := 0; i < 500; i++ {
+		a, b := random(), random()
+		t.Run(fmt.Sprint(i), func(t *testing.T) {
+			ops := Lines(a, b)
+			checkRoundTrip(t, a, b, ops)
+			if want := shortestEdits(splitLines(a), splitLines(b)); edits(ops) != want {
+				t.Fatalf("编辑行数 %d，期望最短 %d", edits(ops), want)
+			}
+		})
+	}
+}
+
+func TestLinesBeyondMaxEditDistance(t *testing.T) {
+	a := make([]string, maxEditDistance)
+	b := make([]string, maxEditDistance)
+	for i := range a {
+		a[i] = fmt.Sprintf("a%d", i)
+		b[i] = fmt.Sprintf("b%d", i)
+	}
+	// 公共的首尾行仍然保留，中间视为整体替换
+	before := "head\n" + strings.Join(a, "\n") + "\ntail"
+	after := "head\n" + strings.Join(b, "\n") + "\ntail"
+
+	ops := Lines(before, after)
+	checkRoundTrip(t, before, after, ops)
+	if len(ops) != 4 || ops[1].Type != Delete || ops[2].Type != Insert {
+		t.Fatalf("超过编辑距离上限应整体替换，实际 %d 段", len(ops))
+	}
+}
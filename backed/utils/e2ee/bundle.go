@@ -0,0 +1,92 @@
+package e2ee
+
+import (
+	"Backed/database/dal"
+	"Backed/database/model"
+	"Backed/utils/realtime"
+	"log"
+	"sync"
+	"time"
+)
+
+// bundleTTL 同一请求方在该时间内重复获取同一用户的密钥包时返回缓存，
+// 每台设备的一次性预密钥最多被一个请求方领取一个，防止反复请求耗尽对方的预密钥
+const bundleTTL = 10 * time.Minute
+
+type bundleKey struct {
+	requesterID int64
+	targetID    int64
+}
+
+type cachedBundle struct {
+	bundles   []DeviceBundle
+	expiresAt time.Time
+}
+
+var (
+	bundleMu    sync.Mutex
+	bundleCache = make(map[bundleKey]cachedBundle)
+	lastSweep   time.Time
+)
+
+// FetchBundle 获取 userID 所有设备的密钥包，同一请求方在缓存期内拿到的是同一份
+func FetchBundle(requesterID, userID int64) ([]DeviceBundle, error) {
+	key := bundleKey{requesterID: requesterID, targetID: userID}
+	now := time.Now()
+
+	bundleMu.Lock()
+	cached, ok := bundleCache[key]
+	bundleMu.Unlock()
+	if ok && now.Before(cached.expiresAt) {
+		return cached.bundles, nil
+	}
+
+	bundles, err := claimBundle(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	bundleMu.Lock()
+	defer bundleMu.Unlock()
+	if len(bundles) > 0 {
+		bundleCache[key] = cachedBundle{bundles: bundles, expiresAt: now.Add(bundleTTL)}
+	}
+	if now.Sub(lastSweep) > time.Minute {
+		for k, v := range bundleCache {
+			if !now.Before(v.expiresAt) {
+				delete(bundleCache, k)
+			}
+		}
+		lastSweep = now
+	}
+	return bundles, nil
+}
+
+// invalidateBundles 用户的设备或签名预密钥变化后，之前缓存的密钥包全部作废
+func invalidateBundles(userID int64) {
+	bundleMu.Lock()
+	defer bundleMu.Unlock()
+	for k := range bundleCache {
+		if k.targetID == userID {
+			delete(bundleCache, k)
+		}
+	}
+}
+
+// notifyLowPreKeys 设备剩余的一次性预密钥不足时提醒其补充，失败只记录日志
+func notifyLowPreKeys(userID int64, deviceID int) {
+	var count int64
+	err := dal.PostgreSQL.Model(&model.OneTimePreKey{}).
+		Where("user_id = ? AND device_id = ?", userID, deviceID).
+		Count(&count).Error
+	if err != nil {
+		log.Printf("统计用户 %d 设备 %d 的预密钥失败: %s", userID, deviceID, err)
+		return
+	}
+	if count < LowPreKeys {
+		realtime.SendToUser(userID, realtime.NewEvent("e2ee.prekeys_low", realtime.H{
+			"device_id": deviceID,
+			"count":     count,
+		}))
+	}
+}
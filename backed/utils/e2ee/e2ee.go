@@ -0,0 +1,530 @@
+package e2ee
+
+import (
+	"Backed/database/dal"
+	"Backed/database/model"
+	"Backed/utils/push"
+	"Backed/utils/realtime"
+	"Backed/utils/retention"
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"sort"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	MaxDevices         = 10        // 每个用户最多登记的设备数
+	MaxPreKeys         = 200       // 每台设备最多保存的一次性预密钥
+	MaxPreKeysPerBatch = 100       // 单次上传的一次性预密钥上限
+	MaxEnvelopeSize    = 64 * 1024 // 单个密文的最大字节数
+	MaxInboxBatch      = 100       // 单次拉取的密文上限
+	LowPreKeys         = 20        // 一次性预密钥低于该数量时提醒设备补充
+)
+
+var (
+	ErrInvalidKey       = errors.New("密钥格式错误")
+	ErrInvalidSignature = errors.New("预密钥签名校验失败")
+	ErrTooManyDevices   = errors.New("设备数量已达上限")
+	ErrTooManyPreKeys   = errors.New("一次性预密钥数量已达上限")
+	ErrDeviceNotFound   = errors.New("设备不存在")
+	ErrDeviceMismatch   = errors.New("密文与接收方设备列表不一致")
+	ErrEnvelopeTooLarge = errors.New("密文过大")
+)
+
+// SignedPreKey 客户端上传的预密钥，签名由设备身份密钥对公钥原文计算
+type SignedPreKey struct {
+	KeyID     int    `json:"key_id"`
+	PublicKey string `json:"public_key"`
+	Signature string `json:"signature,omitempty"`
+}
+
+// DeviceBundle 发起会话所需的一台设备的公开密钥
+type DeviceBundle struct {
+	DeviceID     int           `json:"device_id"`
+	IdentityKey  string        `json:"identity_key"`
+	SignedPreKey SignedPreKey  `json:"signed_pre_key"`
+	OneTimeKey   *SignedPreKey `json:"one_time_pre_key"`
+}
+
+// SendOptions 消息的结构化元数据，服务器据此建立引用关系和提及索引，内容本身仍只在密文中
+type SendOptions struct {
+	ReplacesID      *int64  // 编辑的原消息
+	ReplyToID       *int64  // 回复或引用的消息，必须属于同一会话
+	ForwardedFromID *int64  // 转发的来源消息，转发多条时每条单独发送
+	Mentions        []int64 // 客户端声明的被提及用户
+}
+
+// OutgoingEnvelope 发送方为接收方某台设备加密好的密文
+type OutgoingEnvelope struct {
+	DeviceID int    `json:"device_id"`
+	Type     int    `json:"type"`
+	Content  string `json:"content"`
+}
+
+// MismatchError 发送方的设备列表已过期，需要补发或剔除的设备
+type MismatchError struct {
+	Missing []int `json:"missing_devices"`
+	Extra   []int `json:"extra_devices"`
+}
+
+func (e *MismatchError) Error() string {
+	return ErrDeviceMismatch.Error()
+}
+
+func (e *MismatchError) Unwrap() error {
+	return ErrDeviceMismatch
+}
+
+// decodeKey 解码 base64 公钥，只接受 32 字节的原始公钥，不接受带 0x05 类型前缀的 Curve25519 格式；
+// 身份密钥直接按 Ed25519 验签，不做 XEdDSA 转换
+func decodeKey(encoded string) ([]byte, error) {
+	raw, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(raw) != ed25519.PublicKeySize {
+		return nil, ErrInvalidKey
+	}
+	return raw, nil
+}
+
+// ValidateIdentityKey 身份密钥为 Ed25519 公钥，用于校验预密钥签名
+func ValidateIdentityKey(encoded string) error {
+	_, err := decodeKey(encoded)
+	return err
+}
+
+// VerifyPreKey 校验预密钥格式，签名存在时用身份密钥验证；requireSig 表示签名必填
+func VerifyPreKey(identityKey string, key SignedPreKey, requireSig bool) error {
+	if key.KeyID <= 0 {
+		return ErrInvalidKey
+	}
+	if _, err := decodeKey(key.PublicKey); err != nil {
+		return err
+	}
+	if key.Signature == "" {
+		if requireSig {
+			return ErrInvalidSignature
+		}
+		return nil
+	}
+
+	identity, err := decodeKey(identityKey)
+	if err != nil {
+		return err
+	}
+	sig, err := base64.StdEncoding.DecodeString(key.Signature)
+	if err != nil || len(sig) != ed25519.SignatureSize {
+		return ErrInvalidSignature
+	}
+	// 签名覆盖 32 字节的预密钥公钥
+	pub, _ := decodeKey(key.PublicKey)
+	if !ed25519.Verify(identity, pub, sig) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// RegisterDevice 登记或重置设备；身份密钥变化时旧的预密钥和待收密文全部作废
+func RegisterDevice(userID int64, deviceID int, name, identityKey string, signed SignedPreKey, preKeys []SignedPreKey) error {
+	if err := ValidateIdentityKey(identityKey); err != nil {
+		return err
+	}
+	if err := VerifyPreKey(identityKey, signed, true); err != nil {
+		return err
+	}
+	for _, key := range preKeys {
+		if err := VerifyPreKey(identityKey, key, false); err != nil {
+			return err
+		}
+	}
+
+	err := dal.PostgreSQL.Transaction(func(tx *gorm.DB) error {
+		var existing model.E2EEDevice
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("user_id = ? AND device_id = ?", userID, deviceID).
+			First(&existing).Error
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			var count int64
+			if err := tx.Model(&model.E2EEDevice{}).Where("user_id = ?", userID).Count(&count).Error; err != nil {
+				return err
+			}
+			if count >= MaxDevices {
+				return ErrTooManyDevices
+			}
+		case err != nil:
+			return err
+		case existing.IdentityKey != identityKey:
+			if err := purgeDevice(tx, userID, deviceID); err != nil {
+				return err
+			}
+		}
+
+		device := model.E2EEDevice{
+			UserID:          userID,
+			DeviceID:        deviceID,
+			Name:            name,
+			IdentityKey:     identityKey,
+			SignedPreKeyID:  signed.KeyID,
+			SignedPreKey:    signed.PublicKey,
+			SignedPreKeySig: signed.Signature,
+		}
+		err = tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_id"}, {Name: "device_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"name", "identity_key", "signed_pre_key_id", "signed_pre_key", "signed_pre_key_sig", "updated_at"}),
+		}).Create(&device).Error
+		if err != nil {
+			return err
+		}
+		return addPreKeys(tx, userID, deviceID, preKeys)
+	})
+	if err == nil {
+		invalidateBundles(userID)
+	}
+	return err
+}
+
+// UpdateSignedPreKey 轮换设备的签名预密钥
+func UpdateSignedPreKey(userID int64, deviceID int, signed SignedPreKey) error {
+	device, err := findDevice(dal.PostgreSQL, userID, deviceID)
+	if err != nil {
+		return err
+	}
+	if err := VerifyPreKey(device.IdentityKey, signed, true); err != nil {
+		return err
+	}
+	err = dal.PostgreSQL.Model(device).Updates(map[string]interface{}{
+		"signed_pre_key_id":  signed.KeyID,
+		"signed_pre_key":     signed.PublicKey,
+		"signed_pre_key_sig": signed.Signature,
+	}).Error
+	if err == nil {
+		invalidateBundles(userID)
+	}
+	return err
+}
+
+// AddPreKeys 补充一次性预密钥
+func AddPreKeys(userID int64, deviceID int, preKeys []SignedPreKey) error {
+	device, err := findDevice(dal.PostgreSQL, userID, deviceID)
+	if err != nil {
+		return err
+	}
+	for _, key := range preKeys {
+		if err := VerifyPreKey(device.IdentityKey, key, false); err != nil {
+			return err
+		}
+	}
+	return dal.PostgreSQL.Transaction(func(tx *gorm.DB) error {
+		return addPreKeys(tx, userID, deviceID, preKeys)
+	})
+}
+
+func addPreKeys(tx *gorm.DB, userID int64, deviceID int, preKeys []SignedPreKey) error {
+	if len(preKeys) == 0 {
+		return nil
+	}
+	if len(preKeys) > MaxPreKeysPerBatch {
+		return ErrTooManyPreKeys
+	}
+	var count int64
+	err := tx.Model(&model.OneTimePreKey{}).
+		Where("user_id = ? AND device_id = ?", userID, deviceID).
+		Count(&count).Error
+	if err != nil {
+		return err
+	}
+	if count+int64(len(preKeys)) > MaxPreKeys {
+		return ErrTooManyPreKeys
+	}
+
+	rows := make([]model.OneTimePreKey, 0, len(preKeys))
+	for _, key := range preKeys {
+		rows = append(rows, model.OneTimePreKey{
+			UserID:    userID,
+			DeviceID:  deviceID,
+			KeyID:     key.KeyID,
+			PublicKey: key.PublicKey,
+			Signature: key.Signature,
+		})
+	}
+	// 重复上传的 key_id 以新值为准
+	return tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "device_id"}, {Name: "key_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"public_key", "signature"}),
+	}).Create(&rows).Error
+}
+
+// CountPreKeys 剩余的一次性预密钥数量，客户端据此决定是否补充
+func CountPreKeys(userID int64, deviceID int) (int64, error) {
+	if _, err := findDevice(dal.PostgreSQL, userID, deviceID); err != nil {
+		return 0, err
+	}
+	var count int64
+	err := dal.PostgreSQL.Model(&model.OneTimePreKey{}).
+		Where("user_id = ? AND device_id = ?", userID, deviceID).
+		Count(&count).Error
+	return count, err
+}
+
+// ListDevices 用户已登记的设备
+func ListDevices(userID int64) ([]model.E2EEDevice, error) {
+	var devices []model.E2EEDevice
+	err := dal.PostgreSQL.Where("user_id = ?", userID).Order("device_id").Find(&devices).Error
+	return devices, err
+}
+
+// DeleteDevice 注销设备，连同预密钥和待收密文
+func DeleteDevice(userID int64, deviceID int) error {
+	err := dal.PostgreSQL.Transaction(func(tx *gorm.DB) error {
+		if _, err := findDevice(tx, userID, deviceID); err != nil {
+			return err
+		}
+		if err := purgeDevice(tx, userID, deviceID); err != nil {
+			return err
+		}
+		return tx.Where("user_id = ? AND device_id = ?", userID, deviceID).Delete(&model.E2EEDevice{}).Error
+	})
+	if err == nil {
+		invalidateBundles(userID)
+	}
+	return err
+}
+
+func purgeDevice(tx *gorm.DB, userID int64, deviceID int) error {
+	err := tx.Where("user_id = ? AND device_id = ?", userID, deviceID).Delete(&model.OneTimePreKey{}).Error
+	if err != nil {
+		return err
+	}
+	return tx.Where("recipient_id = ? AND recipient_device = ?", userID, deviceID).Delete(&model.Envelope{}).Error
+}
+
+func findDevice(db *gorm.DB, userID int64, deviceID int) (*model.E2EEDevice, error) {
+	var device model.E2EEDevice
+	err := db.Where("user_id = ? AND device_id = ?", userID, deviceID).First(&device).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrDeviceNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &device, nil
+}
+
+// claimBundle 获取对方所有设备的密钥包，每台设备领取并删除一个一次性预密钥，
+// 预密钥耗尽时 one_time_pre_key 为空，客户端仅用签名预密钥建立会话
+func claimBundle(userID int64) ([]DeviceBundle, error) {
+	devices, err := ListDevices(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	bundles := make([]DeviceBundle, 0, len(devices))
+	for _, device := range devices {
+		bundle := DeviceBundle{
+			DeviceID:    device.DeviceID,
+			IdentityKey: device.IdentityKey,
+			SignedPreKey: SignedPreKey{
+				KeyID:     device.SignedPreKeyID,
+				PublicKey: device.SignedPreKey,
+				Signature: device.SignedPreKeySig,
+			},
+		}
+
+		var claimed []model.OneTimePreKey
+		// SKIP LOCKED 保证并发请求不会领到同一个预密钥
+		err := dal.PostgreSQL.Raw(`DELETE FROM one_time_pre_keys
+			WHERE (user_id, device_id, key_id) IN (
+				SELECT user_id, device_id, key_id FROM one_time_pre_keys
+				WHERE user_id = ? AND device_id = ?
+				ORDER BY key_id LIMIT 1
+				FOR UPDATE SKIP LOCKED)
+			RETURNING *`, userID, device.DeviceID).Scan(&claimed).Error
+		if err != nil {
+			return nil, err
+		}
+		if len(claimed) > 0 {
+			bundle.OneTimeKey = &SignedPreKey{
+				KeyID:     claimed[0].KeyID,
+				PublicKey: claimed[0].PublicKey,
+				Signature: claimed[0].Signature,
+			}
+			notifyLowPreKeys(userID, device.DeviceID)
+		}
+		bundles = append(bundles, bundle)
+	}
+	return bundles, nil
+}
+
+// Send 存储发给接收方每台设备的密文，必须恰好覆盖接收方当前的全部设备；
+// 发给自己时用于多端同步，不包括发送设备本身。replacesID 不为空时作为对该消息的编辑
+func Send(senderID int64, senderDevice int, recipientID int64, opts SendOptions, envelopes []OutgoingEnvelope) (*model.E2EEMessage, error) {
+	if _, err := findDevice(dal.PostgreSQL, senderID, senderDevice); err != nil {
+		return nil, err
+	}
+	mentions, err := mentionedUsers(senderID, recipientID, opts.Mentions)
+	if err != nil {
+		return nil, err
+	}
+
+	devices, err := ListDevices(recipientID)
+	if err != nil {
+		return nil, err
+	}
+	expected := make(map[int]bool, len(devices))
+	for _, device := range devices {
+		if recipientID == senderID && device.DeviceID == senderDevice {
+			continue
+		}
+		expected[device.DeviceID] = true
+	}
+	if len(expected) == 0 {
+		return nil, ErrDeviceNotFound
+	}
+
+	// 过期时间在写入时确定，之后修改会话设置只影响新消息
+	expiresAt, err := retention.ExpiresAt(senderID, recipientID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	rows := make([]model.Envelope, 0, len(envelopes))
+	seen := make(map[int]bool, len(envelopes))
+	mismatch := &MismatchError{}
+	for _, env := range envelopes {
+		content, err := base64.StdEncoding.DecodeString(env.Content)
+		if err != nil || len(content) == 0 {
+			return nil, ErrInvalidKey
+		}
+		if len(content) > MaxEnvelopeSize {
+			return nil, ErrEnvelopeTooLarge
+		}
+		if seen[env.DeviceID] {
+			continue
+		}
+		seen[env.DeviceID] = true
+		if !expected[env.DeviceID] {
+			mismatch.Extra = append(mismatch.Extra, env.DeviceID)
+			continue
+		}
+		rows = append(rows, model.Envelope{
+			SenderID:        senderID,
+			SenderDevice:    senderDevice,
+			RecipientID:     recipientID,
+			RecipientDevice: env.DeviceID,
+			Type:            env.Type,
+			Content:         content,
+			ExpiresAt:       expiresAt,
+		})
+	}
+	for deviceID := range expected {
+		if !seen[deviceID] {
+			mismatch.Missing = append(mismatch.Missing, deviceID)
+		}
+	}
+	if len(mismatch.Missing) > 0 || len(mismatch.Extra) > 0 {
+		sort.Ints(mismatch.Missing)
+		sort.Ints(mismatch.Extra)
+		return nil, mismatch
+	}
+
+	message := &model.E2EEMessage{SenderID: senderID, RecipientID: recipientID, ExpiresAt: expiresAt}
+	err = dal.PostgreSQL.Transaction(func(tx *gorm.DB) error {
+		if opts.ReplacesID != nil {
+			original, err := editableMessage(tx, senderID, recipientID, *opts.ReplacesID)
+			if err != nil {
+				return err
+			}
+			message.ReplacesID = &original.ID
+		}
+		if opts.ReplyToID != nil {
+			replyTo, err := referencedMessage(tx, senderID, *opts.ReplyToID)
+			if err != nil {
+				return err
+			}
+			if !inConversation(replyTo, senderID, recipientID) {
+				return ErrMessageNotFound
+			}
+			message.ReplyToID = &replyTo.ID
+		}
+		if opts.ForwardedFromID != nil {
+			source, err := referencedMessage(tx, senderID, *opts.ForwardedFromID)
+			if err != nil {
+				return err
+			}
+			message.ForwardedFromID = &source.ID
+		}
+		if err := tx.Create(message).Error; err != nil {
+			return err
+		}
+		if message.ReplacesID != nil {
+			err := tx.Model(&model.E2EEMessage{}).Where("id = ?", *message.ReplacesID).
+				Update("edited_at", message.CreatedAt).Error
+			if err != nil {
+				return err
+			}
+		}
+		if err := saveMentions(tx, message, mentions); err != nil {
+			return err
+		}
+		for i := range rows {
+			rows[i].MessageID = message.ID
+		}
+		return tx.Create(&rows).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// 只通知有新密文，各设备自行拉取属于自己的部分
+	deviceIDs := make([]int, 0, len(rows))
+	for _, row := range rows {
+		deviceIDs = append(deviceIDs, row.RecipientDevice)
+	}
+	delivered := realtime.SendToUser(recipientID, realtime.NewEvent("e2ee.envelope", realtime.H{
+		"sender_id":         strconv.FormatInt(senderID, 10),
+		"message_id":        message.ID,
+		"replaces_id":       message.ReplacesID,
+		"reply_to_id":       message.ReplyToID,
+		"forwarded_from_id": message.ForwardedFromID,
+		"mentioned":         len(mentions) > 0,
+		"device_ids":        deviceIDs,
+	}))
+	// 接收方离线时发送推送，服务器无法读取内容，只提示有新消息；编辑不再打扰
+	if delivered == 0 && recipientID != senderID && message.ReplacesID == nil {
+		push.Notify(recipientID, push.Notification{
+			Kind:   push.KindMessage,
+			PeerID: senderID,
+			Body:   "你收到一条新消息",
+		})
+	}
+	return message, nil
+}
+
+// Inbox 拉取发给本设备的密文，确认前会重复返回
+func Inbox(userID int64, deviceID int, afterID int64) ([]model.Envelope, error) {
+	if _, err := findDevice(dal.PostgreSQL, userID, deviceID); err != nil {
+		return nil, err
+	}
+	var envelopes []model.Envelope
+	err := dal.PostgreSQL.
+		Where("recipient_id = ? AND recipient_device = ? AND id > ?", userID, deviceID, afterID).
+		Where("expires_at IS NULL OR expires_at > ?", time.Now()).
+		Order("id").Limit(MaxInboxBatch).
+		Find(&envelopes).Error
+	return envelopes, err
+}
+
+// Ack 确认收到后删除服务器上的密文
+func Ack(userID int64, deviceID int, ids []int64) (int64, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	result := dal.PostgreSQL.
+		Where("recipient_id = ? AND recipient_device = ? AND id IN ?", userID, deviceID, ids).
+		Delete(&model.Envelope{})
+	return result.RowsAffected, result.Error
+}
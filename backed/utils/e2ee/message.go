@@ -0,0 +1,237 @@
+package e2ee
+
+import (
+	"Backed/config"
+	"Backed/database/dal"
+	"Backed/database/model"
+	"Backed/utils/realtime"
+	"errors"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrMessageNotFound = errors.New("消息不存在")
+	ErrMessageRecalled = errors.New("消息已被撤回")
+	ErrRecallExpired   = errors.New("已超过可撤回的时间")
+	ErrEditExpired     = errors.New("已超过可编辑的时间")
+	ErrInvalidMention  = errors.New("只能提及会话中的成员")
+)
+
+var (
+	recallWindow = 2 * time.Minute
+	editWindow   = 24 * time.Hour
+)
+
+// Init 设置撤回和编辑的时间窗口
+func Init(cfg config.MessageConfig) {
+	if cfg.RecallWindowMinutes > 0 {
+		recallWindow = time.Duration(cfg.RecallWindowMinutes) * time.Minute
+	}
+	if cfg.EditWindowMinutes > 0 {
+		editWindow = time.Duration(cfg.EditWindowMinutes) * time.Minute
+	}
+}
+
+// findOriginal 查询参与者可见的消息，编辑版本解析为原消息；lock 表示对原消息加行锁
+func findOriginal(db *gorm.DB, userID, messageID int64, lock bool) (*model.E2EEMessage, error) {
+	var message model.E2EEMessage
+	err := db.Where("id = ? AND (sender_id = ? OR recipient_id = ?)", messageID, userID, userID).First(&message).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrMessageNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if message.ReplacesID == nil && !lock {
+		return &message, nil
+	}
+	if message.ReplacesID != nil {
+		messageID = *message.ReplacesID
+	}
+
+	query := db
+	if lock {
+		query = query.Clauses(clause.Locking{Strength: "UPDATE"})
+	}
+	var original model.E2EEMessage
+	err = query.Where("id = ?", messageID).First(&original).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrMessageNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &original, nil
+}
+
+// editableMessage 校验发送方能否编辑该消息，返回被编辑的原消息
+func editableMessage(tx *gorm.DB, senderID, recipientID, messageID int64) (*model.E2EEMessage, error) {
+	original, err := findOriginal(tx, senderID, messageID, true)
+	if err != nil {
+		return nil, err
+	}
+	if original.SenderID != senderID || original.RecipientID != recipientID {
+		return nil, ErrMessageNotFound
+	}
+	if original.RecalledAt != nil {
+		return nil, ErrMessageRecalled
+	}
+	if time.Since(original.CreatedAt) > editWindow {
+		return nil, ErrEditExpired
+	}
+	return original, nil
+}
+
+// referencedMessage 回复或转发引用的消息，必须是用户参与且未撤回的，编辑版本解析为原消息
+func referencedMessage(tx *gorm.DB, userID, messageID int64) (*model.E2EEMessage, error) {
+	original, err := findOriginal(tx, userID, messageID, false)
+	if err != nil {
+		return nil, err
+	}
+	if original.RecalledAt != nil {
+		return nil, ErrMessageRecalled
+	}
+	return original, nil
+}
+
+// inConversation 消息是否属于两个用户之间的会话
+func inConversation(message *model.E2EEMessage, userID, peerID int64) bool {
+	return (message.SenderID == userID && message.RecipientID == peerID) ||
+		(message.SenderID == peerID && message.RecipientID == userID)
+}
+
+// mentionedUsers 校验并去重客户端声明的提及；只有单聊，能提及的只有对方，
+// 群聊中的 @所有人 及其权限检查要等有了群组之后再做
+func mentionedUsers(senderID, recipientID int64, ids []int64) ([]int64, error) {
+	var users []int64
+	seen := make(map[int64]bool, len(ids))
+	for _, id := range ids {
+		if id != recipientID || id == senderID {
+			return nil, ErrInvalidMention
+		}
+		if !seen[id] {
+			seen[id] = true
+			users = append(users, id)
+		}
+	}
+	return users, nil
+}
+
+// saveMentions 写入提及索引；编辑时以新版本的提及替换原消息上的记录
+func saveMentions(tx *gorm.DB, message *model.E2EEMessage, users []int64) error {
+	messageID := message.ID
+	if message.ReplacesID != nil {
+		messageID = *message.ReplacesID
+		if err := tx.Where("message_id = ?", messageID).Delete(&model.E2EEMention{}).Error; err != nil {
+			return err
+		}
+	}
+	if len(users) == 0 {
+		return nil
+	}
+	rows := make([]model.E2EEMention, 0, len(users))
+	for _, userID := range users {
+		rows = append(rows, model.E2EEMention{MessageID: messageID, UserID: userID})
+	}
+	return tx.Create(&rows).Error
+}
+
+// Recall 撤回消息及其全部编辑版本，删除尚未投递的密文并通知双方；
+// 已撤回时直接返回成功
+func Recall(senderID, messageID int64) (*model.E2EEMessage, error) {
+	var original *model.E2EEMessage
+	var changed bool
+	err := dal.PostgreSQL.Transaction(func(tx *gorm.DB) error {
+		var err error
+		original, err = findOriginal(tx, senderID, messageID, true)
+		if err != nil {
+			return err
+		}
+		if original.SenderID != senderID {
+			return ErrMessageNotFound
+		}
+		if original.RecalledAt != nil {
+			return nil
+		}
+		if time.Since(original.CreatedAt) > recallWindow {
+			return ErrRecallExpired
+		}
+
+		now := time.Now()
+		err = tx.Model(&model.E2EEMessage{}).
+			Where("id = ? OR replaces_id = ?", original.ID, original.ID).
+			Update("recalled_at", now).Error
+		if err != nil {
+			return err
+		}
+		err = tx.Where("message_id IN (?)",
+			tx.Model(&model.E2EEMessage{}).Select("id").Where("id = ? OR replaces_id = ?", original.ID, original.ID)).
+			Delete(&model.Envelope{}).Error
+		if err != nil {
+			return err
+		}
+		original.RecalledAt = &now
+		changed = true
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// 已收到密文的设备根据事件删除本地内容，离线设备上线后通过撤回列表同步
+	if changed {
+		event := realtime.NewEvent("e2ee.recalled", realtime.H{
+			"message_id": original.ID,
+			"sender_id":  strconv.FormatInt(senderID, 10),
+		})
+		realtime.SendToUser(original.RecipientID, event)
+		if original.RecipientID != senderID {
+			realtime.SendToUser(senderID, event)
+		}
+	}
+	return original, nil
+}
+
+// History 消息的原始版本和全部编辑版本，按时间顺序排列；
+// 内容只在客户端解密，服务器返回各版本的ID和时间供客户端对应本地记录
+func History(userID, messageID int64) ([]model.E2EEMessage, error) {
+	original, err := findOriginal(dal.PostgreSQL, userID, messageID, false)
+	if err != nil {
+		return nil, err
+	}
+	if original.RecalledAt != nil {
+		return nil, ErrMessageRecalled
+	}
+
+	var versions []model.E2EEMessage
+	err = dal.PostgreSQL.Where("id = ? OR replaces_id = ?", original.ID, original.ID).
+		Order("id").Find(&versions).Error
+	return versions, err
+}
+
+// Recalled 发给用户且在 since 之后被撤回的消息，供离线设备补齐撤回状态
+func Recalled(userID int64, since time.Time) ([]model.E2EEMessage, error) {
+	var messages []model.E2EEMessage
+	err := dal.PostgreSQL.
+		Where("recipient_id = ? AND replaces_id IS NULL AND recalled_at > ?", userID, since).
+		Order("recalled_at").Limit(MaxInboxBatch).
+		Find(&messages).Error
+	return messages, err
+}
+
+// Mentions 提及用户且未撤回的消息，按时间倒序；before 为上一页最后一条消息的ID，为 0 时从最新开始
+func Mentions(userID, before int64) ([]model.E2EEMessage, error) {
+	query := dal.PostgreSQL.Where("id IN (?) AND recalled_at IS NULL",
+		dal.PostgreSQL.Model(&model.E2EEMention{}).Select("message_id").Where("user_id = ?", userID))
+	if before > 0 {
+		query = query.Where("id < ?", before)
+	}
+
+	var messages []model.E2EEMessage
+	err := query.Order("id DESC").Limit(MaxInboxBatch).Find(&messages).Error
+	return messages, err
+}
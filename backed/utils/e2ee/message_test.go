@@ -0,0 +1,210 @@
+package e2ee
+
+import (
+	"Backed/database/dal"
+	"Backed/database/dal/daltest"
+	"Backed/database/model"
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+)
+
+// setup 登记同一用户的两台设备，消息在两台设备间同步
+func setup(t *testing.T) {
+	t.Helper()
+
+	db := daltest.SQLite(t, &model.User{}, &model.E2EEDevice{}, &model.Envelope{},
+		&model.E2EEMessage{}, &model.E2EEMention{}, &model.ConversationRetention{})
+	if err := db.Create(&model.User{UserID: 1, Username: "alice", Email: "alice@example.com"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []int{1, 2} {
+		if err := db.Create(&model.E2EEDevice{UserID: 1, DeviceID: id, IdentityKey: "key"}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func send(t *testing.T, replacesID *int64) *model.E2EEMessage {
+	t.Helper()
+	content := base64.StdEncoding.EncodeToString([]byte("ciphertext"))
+	message, err := Send(1, 1, 1, SendOptions{ReplacesID: replacesID}, []OutgoingEnvelope{{DeviceID: 2, Type: 1, Content: content}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return message
+}
+
+// backdate 将消息的发送时间提前，模拟超出时间窗口
+func backdate(t *testing.T, id int64, d time.Duration) {
+	t.Helper()
+	err := dal.PostgreSQL.Model(&model.E2EEMessage{}).Where("id = ?", id).
+		Update("created_at", time.Now().Add(-d)).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestEditKeepsHistory(t *testing.T) {
+	setup(t)
+
+	original := send(t, nil)
+	first := send(t, &original.ID)
+	// 对编辑版本再次编辑，仍然归到原消息下
+	second := send(t, &first.ID)
+	if second.ReplacesID == nil || *second.ReplacesID != original.ID {
+		t.Fatalf("编辑应指向原消息，实际 %v", second.ReplacesID)
+	}
+
+	versions, err := History(1, second.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(versions) != 3 || versions[0].ID != original.ID || versions[2].ID != second.ID {
+		t.Fatalf("历史版本错误: %+v", versions)
+	}
+	if versions[0].EditedAt == nil {
+		t.Fatal("原消息未标记为已编辑")
+	}
+
+	var envelopes []model.Envelope
+	dal.PostgreSQL.Where("message_id = ?", second.ID).Find(&envelopes)
+	if len(envelopes) != 1 || envelopes[0].RecipientDevice != 2 {
+		t.Fatalf("编辑后的密文未关联到新版本: %+v", envelopes)
+	}
+
+	backdate(t, original.ID, editWindow+time.Minute)
+	if _, err := Send(1, 1, 1, SendOptions{ReplacesID: &original.ID}, []OutgoingEnvelope{{DeviceID: 2, Type: 1, Content: "Yw=="}}); !errors.Is(err, ErrEditExpired) {
+		t.Fatalf("超过编辑时限应被拒绝，实际 %v", err)
+	}
+}
+
+func TestRecallRemovesUndelivered(t *testing.T) {
+	setup(t)
+
+	original := send(t, nil)
+	edit := send(t, &original.ID)
+	other := send(t, nil)
+
+	recalled, err := Recall(1, edit.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if recalled.ID != original.ID || recalled.RecalledAt == nil {
+		t.Fatalf("撤回编辑版本应撤回原消息: %+v", recalled)
+	}
+
+	var remaining []model.Envelope
+	dal.PostgreSQL.Find(&remaining)
+	if len(remaining) != 1 || remaining[0].MessageID != other.ID {
+		t.Fatalf("只应删除被撤回消息的密文，剩余 %+v", remaining)
+	}
+
+	if _, err := Send(1, 1, 1, SendOptions{ReplacesID: &original.ID}, []OutgoingEnvelope{{DeviceID: 2, Type: 1, Content: "Yw=="}}); !errors.Is(err, ErrMessageRecalled) {
+		t.Fatalf("已撤回的消息不能编辑，实际 %v", err)
+	}
+	if _, err := History(1, original.ID); !errors.Is(err, ErrMessageRecalled) {
+		t.Fatalf("已撤回的消息不再返回历史，实际 %v", err)
+	}
+	// 重复撤回直接成功
+	if _, err := Recall(1, original.ID); err != nil {
+		t.Fatalf("重复撤回应成功，实际 %v", err)
+	}
+
+	list, err := Recalled(1, time.Now().Add(-time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 || list[0].ID != original.ID {
+		t.Fatalf("撤回列表应只包含原消息: %+v", list)
+	}
+
+	backdate(t, other.ID, recallWindow+time.Minute)
+	if _, err := Recall(1, other.ID); !errors.Is(err, ErrRecallExpired) {
+		t.Fatalf("超过撤回时限应被拒绝，实际 %v", err)
+	}
+}
+
+func TestRecallOnlyBySender(t *testing.T) {
+	setup(t)
+	if err := dal.PostgreSQL.Create(&model.User{UserID: 2, Username: "bob", Email: "bob@example.com"}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	message := send(t, nil)
+	if _, err := Recall(2, message.ID); !errors.Is(err, ErrMessageNotFound) {
+		t.Fatalf("非参与者不能撤回，实际 %v", err)
+	}
+	if _, err := History(2, message.ID); !errors.Is(err, ErrMessageNotFound) {
+		t.Fatalf("非参与者不能查看历史，实际 %v", err)
+	}
+}
+
+func TestReplyForwardAndMentions(t *testing.T) {
+	setup(t)
+	for _, row := range []interface{}{
+		&model.User{UserID: 2, Username: "bob", Email: "bob@example.com"},
+		&model.E2EEDevice{UserID: 2, DeviceID: 1, IdentityKey: "key"},
+		&model.User{UserID: 3, Username: "carol", Email: "carol@example.com"},
+	} {
+		if err := dal.PostgreSQL.Create(row).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	toBob := func(opts SendOptions) (*model.E2EEMessage, error) {
+		return Send(1, 1, 2, opts, []OutgoingEnvelope{{DeviceID: 1, Type: 1, Content: "Yw=="}})
+	}
+
+	note := send(t, nil)
+	first, err := toBob(SendOptions{Mentions: []int64{2, 2}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	edit, err := toBob(SendOptions{ReplacesID: &first.ID, Mentions: []int64{2}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 回复编辑版本时引用原消息
+	reply, err := toBob(SendOptions{ReplyToID: &edit.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reply.ReplyToID == nil || *reply.ReplyToID != first.ID {
+		t.Fatalf("回复应指向原消息，实际 %v", reply.ReplyToID)
+	}
+	// 回复只能引用同一会话中的消息，转发可以来自其他会话
+	if _, err := toBob(SendOptions{ReplyToID: &note.ID}); !errors.Is(err, ErrMessageNotFound) {
+		t.Fatalf("不能回复其他会话的消息，实际 %v", err)
+	}
+	forward, err := toBob(SendOptions{ForwardedFromID: &note.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if forward.ForwardedFromID == nil || *forward.ForwardedFromID != note.ID {
+		t.Fatalf("转发来源错误: %v", forward.ForwardedFromID)
+	}
+
+	if _, err := toBob(SendOptions{Mentions: []int64{3}}); !errors.Is(err, ErrInvalidMention) {
+		t.Fatalf("不能提及会话外的用户，实际 %v", err)
+	}
+
+	// 编辑后的提及仍记在原消息上，不会重复
+	mentions, err := Mentions(2, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mentions) != 1 || mentions[0].ID != first.ID {
+		t.Fatalf("提及列表应只包含原消息: %+v", mentions)
+	}
+	if _, err := Recall(1, first.ID); err != nil {
+		t.Fatal(err)
+	}
+	if mentions, _ := Mentions(2, 0); len(mentions) != 0 {
+		t.Fatalf("撤回的消息不应出现在提及列表: %+v", mentions)
+	}
+	if _, err := toBob(SendOptions{ForwardedFromID: &first.ID}); !errors.Is(err, ErrMessageRecalled) {
+		t.Fatalf("不能转发已撤回的消息，实际 %v", err)
+	}
+}
@@ -1,6 +1,9 @@
 package utils
 
 import (
+	"Backed/database/dal"
+	"Backed/database/model"
+	"errors"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 	"net/http"
@@ -12,17 +15,30 @@ const (
 	authHeader   = "Authorization"
 	bearerPrefix = "Bearer "
 	tokenCtxKey  = "jwtClaims"
+	userCtxKey   = "currentUser"
 )
 
 var (
 	jwtKey          = []byte("secret")
 	errNotLoggedIn  = gin.H{"error": "你还没有登录，无法进行此操作"}
 	errInvalidToken = gin.H{"error": "无效的访问令牌"}
+	errTokenRevoked = gin.H{"error": "登录状态已失效，请重新登录"}
+	errNotAdmin     = gin.H{"error": "你没有权限进行此操作"}
+
+	ErrInvalidToken = errors.New("无效的访问令牌")
+	ErrTokenRevoked = errors.New("登录状态已失效")
+	ErrUserBanned   = errors.New("该账号已被封禁")
 )
 
+// 签发时间精确到微秒，强制下线后同一秒内重新登录签发的令牌不会被误判为失效
+func init() {
+	jwt.TimePrecision = time.Microsecond
+}
+
 func GenerateToken(username string) (string, error) {
 	claims := &jwt.RegisteredClaims{
 		Subject:   username,
+		IssuedAt:  jwt.NewNumericDate(time.Now()),
 		ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
 	}
 	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(jwtKey)
@@ -42,20 +58,93 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		token, err := jwt.ParseWithClaims(
-			tokenStr,
-			&jwt.RegisteredClaims{},
-			func(*jwt.Token) (interface{}, error) { return jwtKey, nil },
-		)
-
-		if err != nil || !token.Valid {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, errInvalidToken)
+		user, claims, err := ValidateToken(tokenStr)
+		if err != nil {
+			switch {
+			case errors.Is(err, ErrUserBanned):
+				c.AbortWithStatusJSON(http.StatusForbidden, BanResponse(user))
+			case errors.Is(err, ErrTokenRevoked):
+				c.AbortWithStatusJSON(http.StatusUnauthorized, errTokenRevoked)
+			default:
+				c.AbortWithStatusJSON(http.StatusUnauthorized, errInvalidToken)
+			}
 			return
 		}
 
-		// 存储解析后的Claims
-		if claims, ok := token.Claims.(*jwt.RegisteredClaims); ok {
-			c.Set(tokenCtxKey, claims)
+		// 存储解析后的Claims和当前用户
+		c.Set(tokenCtxKey, claims)
+		c.Set(userCtxKey, user)
+
+		c.Next()
+	}
+}
+
+// OptionalAuthMiddleware 携带有效令牌时加载当前用户，未登录也允许继续访问
+func OptionalAuthMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tokenStr := strings.TrimPrefix(c.GetHeader(authHeader), bearerPrefix)
+		if tokenStr != "" {
+			if user, claims, err := ValidateToken(tokenStr); err == nil {
+				c.Set(tokenCtxKey, claims)
+				c.Set(userCtxKey, user)
+			}
+		}
+
+		c.Next()
+	}
+}
+
+// ValidateToken 校验令牌并加载对应用户，封禁或被强制下线的账号会返回错误
+func ValidateToken(tokenStr string) (*model.User, *jwt.RegisteredClaims, error) {
+	token, err := jwt.ParseWithClaims(
+		tokenStr,
+		&jwt.RegisteredClaims{},
+		func(*jwt.Token) (interface{}, error) { return jwtKey, nil },
+	)
+	if err != nil || !token.Valid {
+		return nil, nil, ErrInvalidToken
+	}
+
+	claims, ok := token.Claims.(*jwt.RegisteredClaims)
+	if !ok {
+		return nil, nil, ErrInvalidToken
+	}
+
+	var user model.User
+	if err := dal.PostgreSQL.Where("username = ?", claims.Subject).First(&user).Error; err != nil {
+		return nil, nil, ErrInvalidToken
+	}
+
+	if user.IsBanActive(time.Now()) {
+		return &user, claims, ErrUserBanned
+	}
+
+	// 强制下线之前签发的令牌全部作废
+	if user.TokensValidAfter != nil {
+		if claims.IssuedAt == nil || claims.IssuedAt.Before(*user.TokensValidAfter) {
+			return &user, claims, ErrTokenRevoked
+		}
+	}
+
+	return &user, claims, nil
+}
+
+// BanResponse 生成封禁提示
+func BanResponse(user *model.User) gin.H {
+	resp := gin.H{"error": ErrUserBanned.Error(), "reason": user.BanReason}
+	if user.BannedUntil != nil {
+		resp["banned_until"] = user.BannedUntil
+	}
+	return resp
+}
+
+// AdminMiddleware 仅允许管理员访问，需放在 AuthMiddleware 之后
+func AdminMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, err := GetCurrentUser(c)
+		if err != nil || !user.IsAdmin {
+			c.AbortWithStatusJSON(http.StatusForbidden, errNotAdmin)
+			return
 		}
 
 		c.Next()
@@ -0,0 +1,62 @@
+package markdown
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderStripsUnsafeHTML(t *testing.T) {
+	cases := []struct {
+		name   string
+		source string
+		banned []string
+	}{
+		{"script 标签", "hello\n\n<script>alert(1)</script>", []string{"<script", "alert(1)"}},
+		{"行内 script", "hi <script>alert(1)</script> there", []string{"<script"}},
+		{"javascript 链接", "[click](javascript:alert(1))", []string{"javascript:"}},
+		{"大小写混合的 javascript 链接", "[click](JaVaScRiPt:alert(1))", []string{"javascript:", "JaVaScRiPt:"}},
+		{"HTML 中的 javascript 链接", `<a href="javascript:alert(1)">x</a>`, []string{"javascript:"}},
+		{"事件属性", `<img src="x.png" onerror="alert(1)">`, []string{"onerror"}},
+		{"div 上的事件属性", `<div onclick="alert(1)">x</div>`, []string{"onclick"}},
+		{"iframe", `<iframe src="https://example.com"></iframe>`, []string{"<iframe"}},
+		{"图片的 javascript 地址", "![x](javascript:alert(1))", []string{"javascript:"}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			html, err := Render(c.source)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, banned := range c.banned {
+				if strings.Contains(html, banned) {
+					t.Fatalf("输出包含 %q: %s", banned, html)
+				}
+			}
+		})
+	}
+}
+
+func TestRenderKeepsMarkdown(t *testing.T) {
+	cases := []struct {
+		source string
+		want   []string
+	}{
+		{"# 标题", []string{"<h1"}},
+		{"**粗体**", []string{"<strong>粗体</strong>"}},
+		{"```go\nfmt.Println()\n```", []string{`<code class="language-go">`}},
+		{"[链接](https://example.com)", []string{`href="https://example.com"`, `rel="nofollow noopener"`, `target="_blank"`}},
+		{"| a | b |\n|---|---|\n| 1 | 2 |", []string{"<table>", "<td>1</td>"}},
+		{"~~删除~~", []string{"<del>删除</del>"}},
+	}
+	for _, c := range cases {
+		html, err := Render(c.source)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, want := range c.want {
+			if !strings.Contains(html, want) {
+				t.Errorf("Render(%q) 缺少 %q: %s", c.source, want, html)
+			}
+		}
+	}
+}
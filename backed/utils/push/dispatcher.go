@@ -0,0 +1,242 @@
+package push
+
+import (
+	"Backed/config"
+	"Backed/database/dal"
+	"Backed/database/model"
+	"Backed/utils/realtime"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 推送类型
+const (
+	KindMessage    = "message"
+	KindMissedCall = "missed_call"
+)
+
+const (
+	sendTimeout = 15 * time.Second
+	messageTTL  = 24 * 60 * 60
+)
+
+// Notification 需要推送给离线用户的一条通知
+type Notification struct {
+	Kind   string
+	PeerID int64 // 会话对方，用于合并推送和会话静音
+	Title  string
+	Body   string
+	Data   map[string]string
+}
+
+type pendingKey struct {
+	userID int64
+	peerID int64
+}
+
+type pending struct {
+	notification Notification
+	count        int
+}
+
+var (
+	webPush        *WebPush
+	collapseWindow = 3 * time.Second
+
+	mu       sync.Mutex
+	pendings = make(map[pendingKey]*pending)
+)
+
+// Init 注册各平台的推送实现
+func Init(cfg config.PushConfig) error {
+	if cfg.CollapseSeconds > 0 {
+		collapseWindow = time.Duration(cfg.CollapseSeconds) * time.Second
+	}
+
+	if cfg.VAPIDPrivateKey == "" {
+		log.Printf("未配置 VAPID 密钥，已临时生成，重启后浏览器需要重新订阅")
+	}
+	wp, err := NewWebPush(cfg.VAPIDPrivateKey, cfg.VAPIDSubject)
+	if err != nil {
+		return err
+	}
+	if cfg.VAPIDPublicKey != "" && cfg.VAPIDPublicKey != wp.PublicKey() {
+		return errors.New("VAPID 公钥与私钥不匹配")
+	}
+	webPush = wp
+	Register(wp)
+	Register(NewRelayProvider(model.PushFCM, cfg.FCMEndpoint))
+	Register(NewRelayProvider(model.PushAPNs, cfg.APNsEndpoint))
+	return nil
+}
+
+// VAPIDPublicKey 浏览器订阅时使用的公钥
+func VAPIDPublicKey() string {
+	if webPush == nil {
+		return ""
+	}
+	return webPush.PublicKey()
+}
+
+// Notify 为离线用户安排一条推送，同一会话在合并窗口内的多条通知只推送一次
+func Notify(userID int64, n Notification) {
+	if realtime.IsOnline(userID) {
+		return
+	}
+
+	key := pendingKey{userID: userID, peerID: n.PeerID}
+	mu.Lock()
+	defer mu.Unlock()
+	if p, ok := pendings[key]; ok {
+		p.notification = n
+		p.count++
+		return
+	}
+	pendings[key] = &pending{notification: n, count: 1}
+	time.AfterFunc(collapseWindow, func() { flush(key) })
+}
+
+func flush(key pendingKey) {
+	mu.Lock()
+	p := pendings[key]
+	delete(pendings, key)
+	mu.Unlock()
+	if p == nil {
+		return
+	}
+
+	// 合并窗口内用户可能已经上线
+	if realtime.IsOnline(key.userID) {
+		return
+	}
+	allowed, err := shouldDeliver(key.userID, key.peerID, time.Now())
+	if err != nil {
+		log.Printf("查询推送设置失败: %s", err)
+		return
+	}
+	if !allowed {
+		return
+	}
+
+	n := p.notification
+	if n.Title == "" && n.PeerID != 0 {
+		var peer model.User
+		if err := dal.PostgreSQL.Select("username").Where("user_id = ?", n.PeerID).First(&peer).Error; err == nil {
+			n.Title = peer.Username
+		}
+	}
+	body := n.Body
+	if p.count > 1 {
+		body = fmt.Sprintf("%s（共 %d 条）", n.Body, p.count)
+	}
+
+	data := map[string]string{
+		"kind":  n.Kind,
+		"count": strconv.Itoa(p.count),
+	}
+	if n.PeerID != 0 {
+		data["peer_id"] = strconv.FormatInt(n.PeerID, 10)
+	}
+	for k, v := range n.Data {
+		data[k] = v
+	}
+	deliver(key.userID, Message{
+		Title:       n.Title,
+		Body:        body,
+		CollapseKey: "conv-" + strconv.FormatInt(n.PeerID, 10),
+		Data:        data,
+		TTL:         messageTTL,
+	})
+}
+
+// deliver 发送到用户的所有设备，失效的令牌直接删除
+func deliver(userID int64, msg Message) {
+	var tokens []model.PushToken
+	if err := dal.PostgreSQL.Where("user_id = ?", userID).Find(&tokens).Error; err != nil {
+		log.Printf("查询推送令牌失败: %s", err)
+		return
+	}
+
+	var wg sync.WaitGroup
+	for i := range tokens {
+		provider, ok := providers[tokens[i].Platform]
+		if !ok {
+			continue
+		}
+		wg.Add(1)
+		go func(token *model.PushToken) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), sendTimeout)
+			defer cancel()
+
+			err := provider.Send(ctx, token, msg)
+			if errors.Is(err, ErrTokenExpired) {
+				if err := dal.PostgreSQL.Delete(token).Error; err != nil {
+					log.Printf("删除失效的推送令牌失败: %s", err)
+				}
+				return
+			}
+			if err != nil {
+				log.Printf("发送 %s 推送失败: %s", token.Platform, err)
+			}
+		}(&tokens[i])
+	}
+	wg.Wait()
+}
+
+// shouldDeliver 检查推送总开关、会话静音和免打扰时段
+func shouldDeliver(userID, peerID int64, now time.Time) (bool, error) {
+	var setting model.PushSetting
+	err := dal.PostgreSQL.Where("user_id = ?", userID).First(&setting).Error
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		// 未设置时使用默认值：开启推送，不启用免打扰
+	case err != nil:
+		return false, err
+	default:
+		if !setting.Enabled {
+			return false, nil
+		}
+		if setting.DNDEnabled && InDND(&setting, now) {
+			return false, nil
+		}
+	}
+
+	if peerID != 0 {
+		var mute model.PushMute
+		err := dal.PostgreSQL.Where("user_id = ? AND peer_id = ?", userID, peerID).First(&mute).Error
+		if err == nil && mute.IsActive(now) {
+			return false, nil
+		}
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// InDND 当前时间是否处于用户所在时区的免打扰时段，结束时间小于开始时间表示跨越零点
+func InDND(setting *model.PushSetting, now time.Time) bool {
+	loc, err := time.LoadLocation(setting.Timezone)
+	if err != nil {
+		loc = time.Local
+	}
+	local := now.In(loc)
+	minute := local.Hour()*60 + local.Minute()
+
+	start, end := setting.DNDStart, setting.DNDEnd
+	if start == end {
+		return false
+	}
+	if start < end {
+		return minute >= start && minute < end
+	}
+	return minute >= start || minute < end
+}
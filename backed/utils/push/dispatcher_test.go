@@ -0,0 +1,186 @@
+package push
+
+import (
+	"Backed/database/dal"
+	"Backed/database/dal/daltest"
+	"Backed/database/model"
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+const fakePlatform = "fake"
+
+// fakeProvider 记录收到的推送，err 不为空时每次发送都返回该错误
+type fakeProvider struct {
+	mu   sync.Mutex
+	sent []Message
+	err  error
+}
+
+func (f *fakeProvider) Platform() string { return fakePlatform }
+
+func (f *fakeProvider) Send(_ context.Context, _ *model.PushToken, msg Message) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.sent = append(f.sent, msg)
+	return f.err
+}
+
+func (f *fakeProvider) messages() []Message {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Message(nil), f.sent...)
+}
+
+// setup 用户 1 登记一个 fake 平台的令牌；
+// 合并窗口设为一小时，由测试直接调用 flush，避免依赖定时器
+func setup(t *testing.T) *fakeProvider {
+	t.Helper()
+
+	db := daltest.SQLite(t, &model.User{}, &model.PushToken{}, &model.PushSetting{}, &model.PushMute{})
+	users := []model.User{
+		{UserID: 1, Username: "alice", Email: "alice@example.com"},
+		{UserID: 2, Username: "bob", Email: "bob@example.com"},
+	}
+	if err := db.Create(&users).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&model.PushToken{UserID: 1, Platform: fakePlatform, Token: "device-1"}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	fake := &fakeProvider{}
+	oldWindow, oldProvider := collapseWindow, providers[fakePlatform]
+	collapseWindow = time.Hour
+	Register(fake)
+	t.Cleanup(func() {
+		collapseWindow = oldWindow
+		if oldProvider != nil {
+			providers[fakePlatform] = oldProvider
+		} else {
+			delete(providers, fakePlatform)
+		}
+		mu.Lock()
+		pendings = make(map[pendingKey]*pending)
+		mu.Unlock()
+	})
+	return fake
+}
+
+func message(body string) Notification {
+	return Notification{Kind: KindMessage, PeerID: 2, Body: body}
+}
+
+func TestNotifyCollapsesWithinWindow(t *testing.T) {
+	fake := setup(t)
+
+	Notify(1, message("第一条"))
+	Notify(1, message("第二条"))
+	Notify(1, message("第三条"))
+	// 另一个会话单独合并
+	Notify(1, Notification{Kind: KindMissedCall, PeerID: 3, Title: "未接来电", Body: "carol"})
+
+	flush(pendingKey{userID: 1, peerID: 2})
+
+	sent := fake.messages()
+	if len(sent) != 1 {
+		t.Fatalf("合并窗口内应只推送一次，实际 %d 次", len(sent))
+	}
+	msg := sent[0]
+	if msg.Body != "第三条（共 3 条）" {
+		t.Fatalf("推送内容 = %q", msg.Body)
+	}
+	if msg.Title != "bob" {
+		t.Fatalf("标题应为会话对方的用户名，实际 %q", msg.Title)
+	}
+	if msg.Data["count"] != "3" || msg.Data["peer_id"] != "2" || msg.CollapseKey != "conv-2" {
+		t.Fatalf("推送数据错误: %+v", msg)
+	}
+
+	// 窗口结束后重新计数
+	flush(pendingKey{userID: 1, peerID: 2})
+	Notify(1, message("第四条"))
+	flush(pendingKey{userID: 1, peerID: 2})
+	sent = fake.messages()
+	if len(sent) != 2 || sent[1].Body != "第四条" {
+		t.Fatalf("新窗口的推送错误: %+v", sent)
+	}
+
+	mu.Lock()
+	_, ok := pendings[pendingKey{userID: 1, peerID: 3}]
+	mu.Unlock()
+	if !ok {
+		t.Fatal("其他会话的待推送通知不应被合并或清除")
+	}
+}
+
+func TestFlushRespectsDND(t *testing.T) {
+	fake := setup(t)
+
+	// 免打扰时段覆盖当前时间的前后一分钟
+	now := time.Now().UTC()
+	minute := now.Hour()*60 + now.Minute()
+	setting := model.PushSetting{
+		UserID:     1,
+		Enabled:    true,
+		DNDEnabled: true,
+		DNDStart:   (minute + 1439) % 1440,
+		DNDEnd:     (minute + 2) % 1440,
+		Timezone:   "UTC",
+	}
+	if err := dal.PostgreSQL.Create(&setting).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	Notify(1, message("免打扰"))
+	flush(pendingKey{userID: 1, peerID: 2})
+	if sent := fake.messages(); len(sent) != 0 {
+		t.Fatalf("免打扰时段不应推送，实际 %+v", sent)
+	}
+
+	// 关闭免打扰后恢复推送
+	dal.PostgreSQL.Model(&setting).Update("dnd_enabled", false)
+	Notify(1, message("恢复"))
+	flush(pendingKey{userID: 1, peerID: 2})
+	if sent := fake.messages(); len(sent) != 1 {
+		t.Fatalf("关闭免打扰后应推送，实际 %d 次", len(sent))
+	}
+}
+
+func TestFlushRespectsMute(t *testing.T) {
+	fake := setup(t)
+
+	if err := dal.PostgreSQL.Create(&model.PushMute{UserID: 1, PeerID: 2}).Error; err != nil {
+		t.Fatal(err)
+	}
+	Notify(1, message("静音"))
+	flush(pendingKey{userID: 1, peerID: 2})
+	if sent := fake.messages(); len(sent) != 0 {
+		t.Fatalf("静音的会话不应推送，实际 %+v", sent)
+	}
+
+	// 静音到期后恢复推送
+	expired := time.Now().Add(-time.Minute)
+	dal.PostgreSQL.Model(&model.PushMute{}).Where("user_id = 1 AND peer_id = 2").Update("until", expired)
+	Notify(1, message("到期"))
+	flush(pendingKey{userID: 1, peerID: 2})
+	if sent := fake.messages(); len(sent) != 1 {
+		t.Fatalf("静音到期后应推送，实际 %d 次", len(sent))
+	}
+}
+
+func TestDeliverRemovesExpiredToken(t *testing.T) {
+	fake := setup(t)
+	fake.err = ErrTokenExpired
+
+	Notify(1, message("失效"))
+	flush(pendingKey{userID: 1, peerID: 2})
+
+	var count int64
+	dal.PostgreSQL.Model(&model.PushToken{}).Where("user_id = ?", 1).Count(&count)
+	if count != 0 {
+		t.Fatal("失效的令牌应被删除")
+	}
+}
@@ -0,0 +1,70 @@
+package push
+
+import (
+	"Backed/database/model"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+// RelayProvider 移动端推送的占位实现，把推送以 JSON 转发到配置的地址，
+// 由接入 FCM/APNs 的转发服务或本地测试用的假服务接收；未配置地址时只记录日志
+type RelayProvider struct {
+	platform string
+	endpoint string
+	client   *http.Client
+}
+
+type relayRequest struct {
+	Platform string `json:"platform"`
+	Token    string `json:"token"`
+	Message
+}
+
+func NewRelayProvider(platform, endpoint string) *RelayProvider {
+	return &RelayProvider{
+		platform: platform,
+		endpoint: endpoint,
+		client:   &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (r *RelayProvider) Platform() string {
+	return r.platform
+}
+
+func (r *RelayProvider) Send(ctx context.Context, token *model.PushToken, msg Message) error {
+	if r.endpoint == "" {
+		log.Printf("未配置 %s 推送地址，跳过推送: %s", r.platform, msg.Title)
+		return nil
+	}
+
+	body, err := json.Marshal(relayRequest{Platform: r.platform, Token: token.Token, Message: msg})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// 转发服务用 410 表示平台已注销该令牌；404 多半是转发地址配置错误，不能据此删除令牌
+	switch {
+	case resp.StatusCode == http.StatusGone:
+		return ErrTokenExpired
+	case resp.StatusCode >= 300:
+		return fmt.Errorf("%s 推送转发返回 %d", r.platform, resp.StatusCode)
+	}
+	return nil
+}
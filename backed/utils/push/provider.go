@@ -0,0 +1,38 @@
+package push
+
+import (
+	"Backed/database/model"
+	"context"
+	"errors"
+)
+
+// ErrTokenExpired 令牌已失效，调用方应删除该令牌
+var ErrTokenExpired = errors.New("推送令牌已失效")
+
+// Message 发给一台设备的推送内容，服务器无法读取加密消息，只发送提示文本
+type Message struct {
+	Title       string            `json:"title"`
+	Body        string            `json:"body"`
+	CollapseKey string            `json:"collapse_key"` // 同一会话的推送在设备上相互替换
+	Data        map[string]string `json:"data,omitempty"`
+	TTL         int               `json:"ttl"` // 推送服务暂存的秒数
+}
+
+// Provider 一种推送平台的实现
+type Provider interface {
+	Platform() string
+	Send(ctx context.Context, token *model.PushToken, msg Message) error
+}
+
+var providers = map[string]Provider{}
+
+// Register 注册推送平台，同一平台后注册的覆盖先注册的
+func Register(provider Provider) {
+	providers[provider.Platform()] = provider
+}
+
+// Supported 平台是否已注册
+func Supported(platform string) bool {
+	_, ok := providers[platform]
+	return ok
+}
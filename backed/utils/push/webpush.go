@@ -0,0 +1,298 @@
+package push
+
+import (
+	"Backed/database/model"
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	recordSize = 4096
+	vapidTTL   = 12 * time.Hour
+)
+
+// pushServiceHosts 浏览器推送服务的域名，订阅地址只能指向这些域名或其子域名
+var pushServiceHosts = []string{
+	"fcm.googleapis.com",        // Chrome、Edge 等基于 Chromium 的浏览器
+	"push.services.mozilla.com", // Firefox
+	"notify.windows.com",        // 旧版 Edge 使用的 WNS
+	"push.apple.com",            // Safari
+}
+
+var errPrivateAddress = errors.New("推送地址指向内网")
+
+// ValidEndpoint 订阅地址由客户端提交，服务器会向其发起请求，只接受已知推送服务的 https 地址
+func ValidEndpoint(raw string) bool {
+	endpoint, err := url.Parse(raw)
+	if err != nil || endpoint.Scheme != "https" || endpoint.User != nil {
+		return false
+	}
+	if port := endpoint.Port(); port != "" && port != "443" {
+		return false
+	}
+	host := strings.ToLower(endpoint.Hostname())
+	for _, allowed := range pushServiceHosts {
+		if host == allowed || strings.HasSuffix(host, "."+allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+// dialPublic 拒绝连接回环、内网和链路本地地址，推送服务域名被解析到内网时同样不会发出请求
+func dialPublic(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsMulticast() {
+		return errPrivateAddress
+	}
+	return nil
+}
+
+// newPublicClient 只访问公网且不跟随重定向的客户端
+func newPublicClient() *http.Client {
+	dialer := &net.Dialer{Timeout: 5 * time.Second, Control: dialPublic}
+	return &http.Client{
+		Timeout: 10 * time.Second,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			ForceAttemptHTTP2:   true,
+			TLSHandshakeTimeout: 5 * time.Second,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// WebPush 按 RFC 8030/8291/8292 直接向浏览器推送服务发送加密消息
+type WebPush struct {
+	privateKey *ecdsa.PrivateKey
+	publicKey  string // base64url 编码的未压缩公钥，前端订阅时作为 applicationServerKey
+	subject    string
+	client     *http.Client
+}
+
+// NewWebPush 使用 base64url 编码的 VAPID 私钥创建，私钥为空时临时生成一对
+func NewWebPush(privateKey, subject string) (*WebPush, error) {
+	var key *ecdsa.PrivateKey
+	if privateKey == "" {
+		generated, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		key = generated
+	} else {
+		raw, err := base64.RawURLEncoding.DecodeString(privateKey)
+		if err != nil {
+			return nil, fmt.Errorf("VAPID 私钥格式错误: %w", err)
+		}
+		ecdhKey, err := ecdh.P256().NewPrivateKey(raw)
+		if err != nil {
+			return nil, fmt.Errorf("VAPID 私钥格式错误: %w", err)
+		}
+		pub := ecdhKey.PublicKey().Bytes()
+		key = &ecdsa.PrivateKey{
+			PublicKey: ecdsa.PublicKey{
+				Curve: elliptic.P256(),
+				X:     new(big.Int).SetBytes(pub[1:33]),
+				Y:     new(big.Int).SetBytes(pub[33:]),
+			},
+			D: new(big.Int).SetBytes(raw),
+		}
+	}
+
+	ecdhKey, err := key.ECDH()
+	if err != nil {
+		return nil, err
+	}
+	return &WebPush{
+		privateKey: key,
+		publicKey:  base64.RawURLEncoding.EncodeToString(ecdhKey.PublicKey().Bytes()),
+		subject:    subject,
+		client:     newPublicClient(),
+	}, nil
+}
+
+func (w *WebPush) Platform() string {
+	return model.PushWeb
+}
+
+// PublicKey 前端订阅时使用的 applicationServerKey
+func (w *WebPush) PublicKey() string {
+	return w.publicKey
+}
+
+func (w *WebPush) Send(ctx context.Context, token *model.PushToken, msg Message) error {
+	if !ValidEndpoint(token.Token) {
+		return ErrTokenExpired
+	}
+	endpoint, err := url.Parse(token.Token)
+	if err != nil {
+		return ErrTokenExpired
+	}
+
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	body, err := encryptPayload(payload, token.P256dh, token.Auth)
+	if err != nil {
+		return ErrTokenExpired
+	}
+
+	authorization, err := w.vapidHeader(endpoint)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, token.Token, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", authorization)
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("Content-Encoding", "aes128gcm")
+	req.Header.Set("TTL", strconv.Itoa(msg.TTL))
+	req.Header.Set("Urgency", "high")
+	if msg.CollapseKey != "" {
+		req.Header.Set("Topic", msg.CollapseKey)
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		return ErrTokenExpired
+	case resp.StatusCode >= 300:
+		return fmt.Errorf("推送服务返回 %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// vapidHeader 生成 RFC 8292 的 Authorization 头，aud 为推送服务的源
+func (w *WebPush) vapidHeader(endpoint *url.URL) (string, error) {
+	claims := jwt.MapClaims{
+		"aud": endpoint.Scheme + "://" + endpoint.Host,
+		"exp": time.Now().Add(vapidTTL).Unix(),
+		"sub": w.subject,
+	}
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodES256, claims).SignedString(w.privateKey)
+	if err != nil {
+		return "", err
+	}
+	return "vapid t=" + signed + ", k=" + w.publicKey, nil
+}
+
+// encryptPayload 按 RFC 8291 使用 aes128gcm 加密，整个负载作为单条记录
+func encryptPayload(payload []byte, p256dh, auth string) ([]byte, error) {
+	asPrivate, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	return encryptWith(payload, p256dh, auth, asPrivate, salt)
+}
+
+// encryptWith 使用给定的临时密钥和盐加密，便于用 RFC 8291 的示例数据校验
+func encryptWith(payload []byte, p256dh, auth string, asPrivate *ecdh.PrivateKey, salt []byte) ([]byte, error) {
+	uaRaw, err := decodeBase64URL(p256dh)
+	if err != nil {
+		return nil, err
+	}
+	authSecret, err := decodeBase64URL(auth)
+	if err != nil {
+		return nil, err
+	}
+	if len(authSecret) != 16 {
+		return nil, errors.New("auth 长度错误")
+	}
+	if len(payload)+1+16 > recordSize-86 {
+		return nil, errors.New("推送内容过长")
+	}
+
+	uaPublic, err := ecdh.P256().NewPublicKey(uaRaw)
+	if err != nil {
+		return nil, err
+	}
+	asPublic := asPrivate.PublicKey().Bytes()
+	sharedSecret, err := asPrivate.ECDH(uaPublic)
+	if err != nil {
+		return nil, err
+	}
+
+	keyInfo := "WebPush: info\x00" + string(uaRaw) + string(asPublic)
+	ikm, err := hkdf.Key(sha256.New, sharedSecret, authSecret, keyInfo, 32)
+	if err != nil {
+		return nil, err
+	}
+
+	cek, err := hkdf.Key(sha256.New, ikm, salt, "Content-Encoding: aes128gcm\x00", 16)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := hkdf.Key(sha256.New, ikm, salt, "Content-Encoding: nonce\x00", 12)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	// 0x02 表示最后一条记录，不额外填充
+	plaintext := append(append([]byte{}, payload...), 0x02)
+
+	header := make([]byte, 0, 21+len(asPublic))
+	header = append(header, salt...)
+	header = binary.BigEndian.AppendUint32(header, recordSize)
+	header = append(header, byte(len(asPublic)))
+	header = append(header, asPublic...)
+	return gcm.Seal(header, nonce, plaintext, nil), nil
+}
+
+// decodeBase64URL 浏览器导出的订阅密钥可能带有填充
+func decodeBase64URL(s string) ([]byte, error) {
+	if raw, err := base64.RawURLEncoding.DecodeString(s); err == nil {
+		return raw, nil
+	}
+	return base64.URLEncoding.DecodeString(s)
+}
@@ -0,0 +1,183 @@
+package push
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/hkdf"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"testing"
+)
+
+// RFC 8291 第 5 节的示例数据
+const (
+	rfcPlaintext = "When I grow up, I want to be a watermelon"
+	rfcASPrivate = "yfWPiYE-n46HLnH0KqZOF1fJJU3MYrct3AELtAQ-oRw"
+	rfcUAPrivate = "q1dXpw3UpT5VOmu_cf_v6ih07Aems3njxI-JWgLcM94"
+	rfcUAPublic  = "BCVxsr7N_eNgVRqvHtD0zTZsEc6-VV-JvLexhqUzORcxaOzi6-AYWXvTBHm4bjyPjs7Vd8pZGH6SRpkNtoIAiw4"
+	rfcSalt      = "DGv6ra1nlYgDCS1FRnbzlw"
+	rfcAuth      = "BTBZMqHH6r4Tts7J_aSIgg"
+	rfcMessage   = "DGv6ra1nlYgDCS1FRnbzlwAAEABBBP4z9KsN6nGRTbVYI_c7VJSPQTBtkgcy27mlmlMoZIIgDll6e3vCYLocInmYWAmS6TlzAC8wEqKK6PBru3jl7A_yl95bQpu6cVPTpK4Mqgkf1CXztLVBSt2Ks3oZwbuwXPXLWyouBWLVWGNWQexSgSxsj_Qulcy4a-fN"
+)
+
+func mustDecode(t *testing.T, s string) []byte {
+	t.Helper()
+	raw, err := decodeBase64URL(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return raw
+}
+
+func TestEncryptMatchesRFC8291(t *testing.T) {
+	asPrivate, err := ecdh.P256().NewPrivateKey(mustDecode(t, rfcASPrivate))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body, err := encryptWith([]byte(rfcPlaintext), rfcUAPublic, rfcAuth, asPrivate, mustDecode(t, rfcSalt))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := base64.RawURLEncoding.EncodeToString(body); got != rfcMessage {
+		t.Fatalf("加密结果与 RFC 示例不一致:\n%s\n期望\n%s", got, rfcMessage)
+	}
+}
+
+// decryptPayload 以浏览器一方的身份解密，按 RFC 8291 的步骤独立推导密钥
+func decryptPayload(t *testing.T, body []byte, uaPrivate *ecdh.PrivateKey, authSecret []byte) []byte {
+	t.Helper()
+	if len(body) < 21 {
+		t.Fatal("密文过短")
+	}
+	salt := body[:16]
+	if rs := binary.BigEndian.Uint32(body[16:20]); rs != recordSize {
+		t.Fatalf("记录大小 = %d", rs)
+	}
+	idLen := int(body[20])
+	asPublicRaw := body[21 : 21+idLen]
+	ciphertext := body[21+idLen:]
+
+	asPublic, err := ecdh.P256().NewPublicKey(asPublicRaw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shared, err := uaPrivate.ECDH(asPublic)
+	if err != nil {
+		t.Fatal(err)
+	}
+	uaPublic := uaPrivate.PublicKey().Bytes()
+	ikm, err := hkdf.Key(sha256.New, shared, authSecret, "WebPush: info\x00"+string(uaPublic)+string(asPublicRaw), 32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cek, _ := hkdf.Key(sha256.New, ikm, salt, "Content-Encoding: aes128gcm\x00", 16)
+	nonce, _ := hkdf.Key(sha256.New, ikm, salt, "Content-Encoding: nonce\x00", 12)
+
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		t.Fatalf("解密失败: %s", err)
+	}
+	// 去掉末尾的填充和记录分隔符
+	end := bytes.LastIndexByte(plaintext, 0x02)
+	if end < 0 {
+		t.Fatal("缺少最后一条记录的分隔符")
+	}
+	return plaintext[:end]
+}
+
+func TestDecryptRFC8291Message(t *testing.T) {
+	uaPrivate, err := ecdh.P256().NewPrivateKey(mustDecode(t, rfcUAPrivate))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := decryptPayload(t, mustDecode(t, rfcMessage), uaPrivate, mustDecode(t, rfcAuth))
+	if string(got) != rfcPlaintext {
+		t.Fatalf("解密结果 = %q", got)
+	}
+}
+
+func TestEncryptRoundTrip(t *testing.T) {
+	uaPrivate, err := ecdh.P256().NewPrivateKey(mustDecode(t, rfcUAPrivate))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	payload := []byte(`{"title":"alice","body":"你收到一条新消息"}`)
+	body, err := encryptPayload(payload, rfcUAPublic, rfcAuth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := decryptPayload(t, body, uaPrivate, mustDecode(t, rfcAuth)); !bytes.Equal(got, payload) {
+		t.Fatalf("往返结果不一致: %q", got)
+	}
+
+	// 每次加密使用新的临时密钥和盐
+	again, err := encryptPayload(payload, rfcUAPublic, rfcAuth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(body[:16], again[:16]) {
+		t.Fatal("两次加密使用了相同的盐")
+	}
+}
+
+func TestValidEndpoint(t *testing.T) {
+	cases := []struct {
+		endpoint string
+		want     bool
+	}{
+		{"https://fcm.googleapis.com/fcm/send/abc", true},
+		{"https://updates.push.services.mozilla.com/wpush/v2/abc", true},
+		{"https://db5p.notify.windows.com/w/?token=abc", true},
+		{"https://web.push.apple.com/abc", true},
+		{"https://FCM.googleapis.com/fcm/send/abc", true},
+		{"http://fcm.googleapis.com/fcm/send/abc", false},
+		{"https://fcm.googleapis.com:8443/fcm/send/abc", false},
+		{"https://user@fcm.googleapis.com/fcm/send/abc", false},
+		{"https://evilfcm.googleapis.com.example.com/abc", false},
+		{"https://notfcm.googleapis.com.evil/abc", false},
+		{"https://10.0.0.1/abc", false},
+		{"https://169.254.169.254/latest/meta-data", false},
+		{"https://localhost/abc", false},
+	}
+	for _, c := range cases {
+		if got := ValidEndpoint(c.endpoint); got != c.want {
+			t.Errorf("ValidEndpoint(%q) = %v，期望 %v", c.endpoint, got, c.want)
+		}
+	}
+}
+
+func TestDialPublicRejectsPrivate(t *testing.T) {
+	cases := []struct {
+		address string
+		allowed bool
+	}{
+		{"142.250.72.10:443", true},
+		{"[2606:4700::1111]:443", true},
+		{"127.0.0.1:443", false},
+		{"10.1.2.3:443", false},
+		{"192.168.1.1:443", false},
+		{"169.254.169.254:443", false},
+		{"[::1]:443", false},
+		{"[fe80::1]:443", false},
+		{"0.0.0.0:443", false},
+	}
+	for _, c := range cases {
+		err := dialPublic("tcp", c.address, nil)
+		if (err == nil) != c.allowed {
+			t.Errorf("dialPublic(%q) = %v，期望允许 %v", c.address, err, c.allowed)
+		}
+	}
+}
@@ -0,0 +1,207 @@
+package realtime
+
+import (
+	"encoding/json"
+	"log"
+	"sync"
+)
+
+// Event 实时通道上传输的事件，客户端与服务端使用相同格式
+type Event struct {
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data,omitempty"`
+}
+
+// NewEvent 将数据序列化为事件
+func NewEvent(eventType string, data interface{}) Event {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		log.Printf("序列化实时事件失败(%s): %s", eventType, err)
+		raw = nil
+	}
+	return Event{Type: eventType, Data: raw}
+}
+
+// H 事件数据的简写
+type H map[string]interface{}
+
+// Client 一个已认证的实时连接，WebSocket 等传输方式各自实现
+type Client interface {
+	ID() string
+	UserID() int64
+	// Send 非阻塞发送，缓冲区已满或连接已关闭时返回 false
+	Send(event Event) bool
+	Close()
+}
+
+// HandlerFunc 处理客户端发来的某一类事件
+type HandlerFunc func(client Client, data json.RawMessage)
+
+// ConnectFunc 连接建立后的回调
+type ConnectFunc func(client Client)
+
+// DisconnectFunc 连接断开后的回调
+type DisconnectFunc func(userID int64, clientID string)
+
+type hub struct {
+	mu           sync.RWMutex
+	clients      map[int64]map[string]Client
+	handlers     map[string]HandlerFunc
+	onConnect    []ConnectFunc
+	onDisconnect []DisconnectFunc
+	// topics 按主题登记订阅的连接，subscriptions 记录每个连接订阅的主题，断开时一并清理
+	topics        map[string]map[Client]struct{}
+	subscriptions map[Client]map[string]struct{}
+	topicRules    []topicRule
+}
+
+var defaultHub = &hub{
+	clients:       make(map[int64]map[string]Client),
+	handlers:      make(map[string]HandlerFunc),
+	topics:        make(map[string]map[Client]struct{}),
+	subscriptions: make(map[Client]map[string]struct{}),
+}
+
+// Handle 注册事件处理函数，应在服务启动前调用
+func Handle(eventType string, handler HandlerFunc) {
+	defaultHub.mu.Lock()
+	defer defaultHub.mu.Unlock()
+	defaultHub.handlers[eventType] = handler
+}
+
+// OnConnect 注册连接建立回调，应在服务启动前调用
+func OnConnect(fn ConnectFunc) {
+	defaultHub.mu.Lock()
+	defer defaultHub.mu.Unlock()
+	defaultHub.onConnect = append(defaultHub.onConnect, fn)
+}
+
+// OnDisconnect 注册连接断开回调，应在服务启动前调用
+func OnDisconnect(fn DisconnectFunc) {
+	defaultHub.mu.Lock()
+	defer defaultHub.mu.Unlock()
+	defaultHub.onDisconnect = append(defaultHub.onDisconnect, fn)
+}
+
+// Register 登记一个新连接并触发连接回调
+func Register(client Client) {
+	defaultHub.mu.Lock()
+	userClients, ok := defaultHub.clients[client.UserID()]
+	if !ok {
+		userClients = make(map[string]Client)
+		defaultHub.clients[client.UserID()] = userClients
+	}
+	userClients[client.ID()] = client
+	callbacks := defaultHub.onConnect
+	defaultHub.mu.Unlock()
+
+	for _, fn := range callbacks {
+		fn(client)
+	}
+}
+
+// Unregister 移除连接并触发断开回调
+func Unregister(client Client) {
+	defaultHub.mu.Lock()
+	userClients := defaultHub.clients[client.UserID()]
+	if _, ok := userClients[client.ID()]; !ok {
+		defaultHub.mu.Unlock()
+		return
+	}
+	delete(userClients, client.ID())
+	if len(userClients) == 0 {
+		delete(defaultHub.clients, client.UserID())
+	}
+	defaultHub.unsubscribeAll(client)
+	callbacks := defaultHub.onDisconnect
+	defaultHub.mu.Unlock()
+
+	for _, fn := range callbacks {
+		fn(client.UserID(), client.ID())
+	}
+}
+
+// Dispatch 将客户端事件交给对应的处理函数
+func Dispatch(client Client, event Event) {
+	defaultHub.mu.RLock()
+	handler, ok := defaultHub.handlers[event.Type]
+	defaultHub.mu.RUnlock()
+
+	if !ok {
+		client.Send(NewEvent("error", H{"message": "未知的事件类型", "ref": event.Type}))
+		return
+	}
+	handler(client, event.Data)
+}
+
+// SendToUser 向用户的所有连接发送事件，返回成功送达的连接数
+func SendToUser(userID int64, event Event) int {
+	delivered := 0
+	for _, client := range userClients(userID) {
+		if client.Send(event) {
+			delivered++
+		}
+	}
+	return delivered
+}
+
+// SendToUserExcept 向用户除指定连接外的其他连接发送事件
+func SendToUserExcept(userID int64, exceptClientID string, event Event) int {
+	delivered := 0
+	for _, client := range userClients(userID) {
+		if client.ID() != exceptClientID && client.Send(event) {
+			delivered++
+		}
+	}
+	return delivered
+}
+
+// SendToClient 向用户的指定连接发送事件
+func SendToClient(userID int64, clientID string, event Event) bool {
+	defaultHub.mu.RLock()
+	client, ok := defaultHub.clients[userID][clientID]
+	defaultHub.mu.RUnlock()
+
+	return ok && client.Send(event)
+}
+
+// FindClient 查询用户的指定连接
+func FindClient(userID int64, clientID string) (Client, bool) {
+	defaultHub.mu.RLock()
+	defer defaultHub.mu.RUnlock()
+	client, ok := defaultHub.clients[userID][clientID]
+	return client, ok
+}
+
+// IsOnline 判断用户是否有在线连接
+func IsOnline(userID int64) bool {
+	defaultHub.mu.RLock()
+	defer defaultHub.mu.RUnlock()
+	return len(defaultHub.clients[userID]) > 0
+}
+
+// HasClient 判断用户的指定连接是否仍然在线
+func HasClient(userID int64, clientID string) bool {
+	defaultHub.mu.RLock()
+	defer defaultHub.mu.RUnlock()
+	_, ok := defaultHub.clients[userID][clientID]
+	return ok
+}
+
+// DisconnectUser 断开用户的全部连接，用于封禁和强制下线
+func DisconnectUser(userID int64) {
+	for _, client := range userClients(userID) {
+		client.Close()
+	}
+}
+
+func userClients(userID int64) []Client {
+	defaultHub.mu.RLock()
+	defer defaultHub.mu.RUnlock()
+
+	clients := make([]Client, 0, len(defaultHub.clients[userID]))
+	for _, client := range defaultHub.clients[userID] {
+		clients = append(clients, client)
+	}
+	return clients
+}
@@ -0,0 +1,133 @@
+package realtime
+
+import (
+	"Backed/utils"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+)
+
+// sseClient 用于无法使用 WebSocket 的网络，下行通过 SSE 推送，上行通过 HTTP 请求提交
+type sseClient struct {
+	id        string
+	userID    int64
+	send      chan Event
+	closeOnce sync.Once
+	done      chan struct{}
+}
+
+func (c *sseClient) ID() string    { return c.id }
+func (c *sseClient) UserID() int64 { return c.userID }
+
+func (c *sseClient) Send(event Event) bool {
+	select {
+	case <-c.done:
+		return false
+	default:
+	}
+
+	select {
+	case c.send <- event:
+		return true
+	default:
+		c.Close()
+		return false
+	}
+}
+
+func (c *sseClient) Close() {
+	c.closeOnce.Do(func() {
+		close(c.done)
+	})
+}
+
+// ServeSSE 建立 SSE 连接，每条消息的数据与 WebSocket 上的事件格式完全相同，
+// 客户端可以直接复用同一套事件处理逻辑
+func ServeSSE(c *gin.Context) {
+	if !checkOrigin(c.Request) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "不允许的来源"})
+		return
+	}
+	user, ok := authenticate(c)
+	if !ok {
+		return
+	}
+
+	client := &sseClient{
+		id:     NewClientID(),
+		userID: user.UserID,
+		send:   make(chan Event, sendBufferSize),
+		done:   make(chan struct{}),
+	}
+
+	header := c.Writer.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Connection", "keep-alive")
+	header.Set("X-Accel-Buffering", "no") // 避免反向代理缓冲
+	c.Status(http.StatusOK)
+
+	client.Send(NewEvent("ready", H{"client_id": client.id, "user_id": strconv.FormatInt(user.UserID, 10)}))
+	Register(client)
+	defer func() {
+		Unregister(client)
+		client.Close()
+	}()
+
+	ticker := time.NewTicker(pingPeriod)
+	defer ticker.Stop()
+
+	var seq uint64
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-client.done:
+			return
+		case event := <-client.send:
+			seq++
+			err := sse.Encode(c.Writer, sse.Event{Id: strconv.FormatUint(seq, 10), Data: event})
+			if err != nil {
+				return
+			}
+			c.Writer.Flush()
+		case <-ticker.C:
+			// 注释行作为心跳，防止中间代理因空闲断开连接
+			if _, err := c.Writer.WriteString(": ping\n\n"); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		}
+	}
+}
+
+// HandleSend 接收 SSE 客户端上行的事件，client_id 为 ready 事件中返回的连接ID，
+// 处理结果通过该连接的 SSE 流返回
+func HandleSend(c *gin.Context) {
+	user, err := utils.GetCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "无法获取用户信息"})
+		return
+	}
+
+	client, ok := FindClient(user.UserID, c.PostForm("client_id"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "连接不存在或已断开"})
+		return
+	}
+
+	eventType := c.PostForm("type")
+	data := c.PostForm("data")
+	if eventType == "" || len(data) > maxMessageSize || (data != "" && !json.Valid([]byte(data))) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的事件格式"})
+		return
+	}
+
+	Dispatch(client, Event{Type: eventType, Data: json.RawMessage(data)})
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
@@ -0,0 +1,73 @@
+package realtime
+
+import (
+	"Backed/utils"
+	"crypto/rand"
+	"encoding/base64"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ticketTTL 连接票据的有效期，只需覆盖获取票据到发起连接之间的时间
+const ticketTTL = 30 * time.Second
+
+type ticket struct {
+	token   string
+	expires time.Time
+}
+
+// tickets 浏览器的 WebSocket 和 EventSource 无法设置请求头，令牌只能放在地址中，
+// 而访问日志和代理会记录完整地址；因此用一次性的短期票据代替长期令牌
+var tickets = struct {
+	sync.Mutex
+	m map[string]ticket
+}{m: make(map[string]ticket)}
+
+// IssueTicket 为当前登录的令牌签发一次性连接票据
+func IssueTicket(c *gin.Context) {
+	if _, err := utils.GetCurrentUser(c); err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "无法获取用户信息"})
+		return
+	}
+	// AuthMiddleware 已确认请求头中带有有效令牌
+	token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+
+	c.JSON(http.StatusOK, gin.H{"ticket": newTicket(token), "expires_in": int(ticketTTL.Seconds())})
+}
+
+func newTicket(token string) string {
+	buf := make([]byte, 24)
+	rand.Read(buf)
+	id := base64.RawURLEncoding.EncodeToString(buf)
+
+	now := time.Now()
+	tickets.Lock()
+	defer tickets.Unlock()
+	// 顺带清理过期票据，票据数量不会超过最近 30 秒内的连接数
+	for key, t := range tickets.m {
+		if now.After(t.expires) {
+			delete(tickets.m, key)
+		}
+	}
+	tickets.m[id] = ticket{token: token, expires: now.Add(ticketTTL)}
+	return id
+}
+
+// redeemTicket 兑换票据对应的令牌，票据无论是否过期都只能使用一次
+func redeemTicket(id string) (string, bool) {
+	tickets.Lock()
+	defer tickets.Unlock()
+	t, ok := tickets.m[id]
+	if !ok {
+		return "", false
+	}
+	delete(tickets.m, id)
+	if time.Now().After(t.expires) {
+		return "", false
+	}
+	return t.token, true
+}
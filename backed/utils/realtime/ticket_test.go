@@ -0,0 +1,32 @@
+package realtime
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTicketSingleUse(t *testing.T) {
+	id := newTicket("token")
+	if token, ok := redeemTicket(id); !ok || token != "token" {
+		t.Fatalf("首次兑换应成功，实际 %q %v", token, ok)
+	}
+	if _, ok := redeemTicket(id); ok {
+		t.Fatal("票据只能使用一次")
+	}
+	if _, ok := redeemTicket("unknown"); ok {
+		t.Fatal("未签发的票据不能兑换")
+	}
+}
+
+func TestTicketExpires(t *testing.T) {
+	id := newTicket("token")
+	tickets.Lock()
+	entry := tickets.m[id]
+	entry.expires = time.Now().Add(-time.Second)
+	tickets.m[id] = entry
+	tickets.Unlock()
+
+	if _, ok := redeemTicket(id); ok {
+		t.Fatal("过期的票据不能兑换")
+	}
+}
@@ -0,0 +1,151 @@
+package realtime
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// maxTopicsPerClient 单个连接最多订阅的主题数
+const maxTopicsPerClient = 32
+
+// TopicCheck 判断连接能否订阅某个主题
+type TopicCheck func(client Client, topic string) bool
+
+type topicRule struct {
+	prefix string
+	check  TopicCheck
+}
+
+// AllowTopic 允许客户端订阅以 prefix 开头的主题，check 为空时所有已认证连接都可订阅；应在服务启动前调用
+func AllowTopic(prefix string, check TopicCheck) {
+	defaultHub.mu.Lock()
+	defer defaultHub.mu.Unlock()
+	defaultHub.topicRules = append(defaultHub.topicRules, topicRule{prefix: prefix, check: check})
+}
+
+type topicRequest struct {
+	Topic string `json:"topic"`
+}
+
+func handleSubscribe(client Client, data json.RawMessage) {
+	var req topicRequest
+	if err := json.Unmarshal(data, &req); err != nil || req.Topic == "" {
+		client.Send(NewEvent("error", H{"message": "无效的订阅请求", "ref": "subscribe"}))
+		return
+	}
+	if !topicAllowed(client, req.Topic) {
+		client.Send(NewEvent("error", H{"message": "无法订阅该主题", "ref": "subscribe", "topic": req.Topic}))
+		return
+	}
+	if !Subscribe(client, req.Topic) {
+		client.Send(NewEvent("error", H{"message": "订阅数量已达上限", "ref": "subscribe", "topic": req.Topic}))
+		return
+	}
+	client.Send(NewEvent("subscribed", H{"topic": req.Topic}))
+}
+
+func handleUnsubscribe(client Client, data json.RawMessage) {
+	var req topicRequest
+	if err := json.Unmarshal(data, &req); err != nil || req.Topic == "" {
+		client.Send(NewEvent("error", H{"message": "无效的订阅请求", "ref": "unsubscribe"}))
+		return
+	}
+	Unsubscribe(client, req.Topic)
+	client.Send(NewEvent("unsubscribed", H{"topic": req.Topic}))
+}
+
+func topicAllowed(client Client, topic string) bool {
+	defaultHub.mu.RLock()
+	rules := defaultHub.topicRules
+	defaultHub.mu.RUnlock()
+
+	for _, rule := range rules {
+		if strings.HasPrefix(topic, rule.prefix) && len(topic) > len(rule.prefix) {
+			return rule.check == nil || rule.check(client, topic)
+		}
+	}
+	return false
+}
+
+// Subscribe 让连接订阅主题，超过单连接上限或连接已断开时返回 false
+func Subscribe(client Client, topic string) bool {
+	defaultHub.mu.Lock()
+	defer defaultHub.mu.Unlock()
+
+	// 连接断开后不再登记，避免订阅残留
+	if _, ok := defaultHub.clients[client.UserID()][client.ID()]; !ok {
+		return false
+	}
+	subs, ok := defaultHub.subscriptions[client]
+	if !ok {
+		subs = make(map[string]struct{})
+		defaultHub.subscriptions[client] = subs
+	}
+	if _, ok := subs[topic]; ok {
+		return true
+	}
+	if len(subs) >= maxTopicsPerClient {
+		return false
+	}
+	subs[topic] = struct{}{}
+
+	members, ok := defaultHub.topics[topic]
+	if !ok {
+		members = make(map[Client]struct{})
+		defaultHub.topics[topic] = members
+	}
+	members[client] = struct{}{}
+	return true
+}
+
+// Unsubscribe 取消连接对主题的订阅
+func Unsubscribe(client Client, topic string) {
+	defaultHub.mu.Lock()
+	defer defaultHub.mu.Unlock()
+
+	if subs, ok := defaultHub.subscriptions[client]; ok {
+		delete(subs, topic)
+		if len(subs) == 0 {
+			delete(defaultHub.subscriptions, client)
+		}
+	}
+	defaultHub.removeFromTopic(client, topic)
+}
+
+// Publish 向订阅了主题的所有连接发送事件，返回成功送达的连接数
+func Publish(topic string, event Event) int {
+	defaultHub.mu.RLock()
+	members := make([]Client, 0, len(defaultHub.topics[topic]))
+	for client := range defaultHub.topics[topic] {
+		members = append(members, client)
+	}
+	defaultHub.mu.RUnlock()
+
+	delivered := 0
+	for _, client := range members {
+		if client.Send(event) {
+			delivered++
+		}
+	}
+	return delivered
+}
+
+// unsubscribeAll 调用方需持有写锁
+func (h *hub) unsubscribeAll(client Client) {
+	for topic := range h.subscriptions[client] {
+		h.removeFromTopic(client, topic)
+	}
+	delete(h.subscriptions, client)
+}
+
+// removeFromTopic 调用方需持有写锁
+func (h *hub) removeFromTopic(client Client, topic string) {
+	members, ok := h.topics[topic]
+	if !ok {
+		return
+	}
+	delete(members, client)
+	if len(members) == 0 {
+		delete(h.topics, topic)
+	}
+}
@@ -0,0 +1,206 @@
+package realtime
+
+import (
+	"Backed/config"
+	"Backed/database/model"
+	"Backed/utils"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	writeWait      = 10 * time.Second
+	pongWait       = 60 * time.Second
+	pingPeriod     = pongWait * 9 / 10
+	maxMessageSize = 64 << 10 // SDP 可能较大
+	sendBufferSize = 64
+)
+
+var allowedOrigins = map[string]bool{}
+
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  4096,
+	WriteBufferSize: 4096,
+	CheckOrigin:     checkOrigin,
+}
+
+// Init 设置允许建立实时连接的前端来源，并注册主题订阅事件
+func Init(cfg config.AppConfig) {
+	for _, host := range []string{cfg.AppHost, cfg.FrontHost} {
+		if host != "" {
+			allowedOrigins[host] = true
+		}
+	}
+	Handle("subscribe", handleSubscribe)
+	Handle("unsubscribe", handleUnsubscribe)
+}
+
+// checkOrigin 只允许同源或配置中的前端地址，非浏览器客户端不带 Origin
+func checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	return u.Host == r.Host || allowedOrigins[u.Host]
+}
+
+type wsClient struct {
+	id        string
+	userID    int64
+	conn      *websocket.Conn
+	send      chan Event
+	closeOnce sync.Once
+	done      chan struct{}
+}
+
+func (c *wsClient) ID() string    { return c.id }
+func (c *wsClient) UserID() int64 { return c.userID }
+
+func (c *wsClient) Send(event Event) bool {
+	select {
+	case <-c.done:
+		return false
+	default:
+	}
+
+	select {
+	case c.send <- event:
+		return true
+	default:
+		// 客户端消费过慢，直接断开让其重连
+		c.Close()
+		return false
+	}
+}
+
+func (c *wsClient) Close() {
+	c.closeOnce.Do(func() {
+		close(c.done)
+		c.conn.Close()
+	})
+}
+
+// authenticate 校验实时连接的身份，浏览器通过 ticket 参数携带 IssueTicket 签发的一次性票据，
+// 其他客户端可以直接在请求头中携带令牌；长期令牌不接受放在地址中。失败时直接写入响应
+func authenticate(c *gin.Context) (*model.User, bool) {
+	tokenStr := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if id := c.Query("ticket"); id != "" {
+		var ok bool
+		tokenStr, ok = redeemTicket(id)
+		if !ok {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "连接票据无效或已过期"})
+			return nil, false
+		}
+	}
+
+	user, _, err := utils.ValidateToken(tokenStr)
+	if err != nil {
+		if errors.Is(err, utils.ErrUserBanned) {
+			c.JSON(http.StatusForbidden, utils.BanResponse(user))
+			return nil, false
+		}
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return user, true
+}
+
+// ServeWebSocket 建立WebSocket连接
+func ServeWebSocket(c *gin.Context) {
+	user, ok := authenticate(c)
+	if !ok {
+		return
+	}
+
+	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		return // Upgrade 已经写入了错误响应
+	}
+
+	client := &wsClient{
+		id:     NewClientID(),
+		userID: user.UserID,
+		conn:   conn,
+		send:   make(chan Event, sendBufferSize),
+		done:   make(chan struct{}),
+	}
+	client.Send(NewEvent("ready", H{"client_id": client.id, "user_id": strconv.FormatInt(user.UserID, 10)}))
+	Register(client)
+
+	go client.writePump()
+	client.readPump()
+}
+
+func (c *wsClient) readPump() {
+	defer func() {
+		Unregister(c)
+		c.Close()
+	}()
+
+	c.conn.SetReadLimit(maxMessageSize)
+	c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
+	for {
+		_, message, err := c.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		var event Event
+		if err := json.Unmarshal(message, &event); err != nil || event.Type == "" {
+			c.Send(NewEvent("error", H{"message": "无效的事件格式"}))
+			continue
+		}
+		Dispatch(c, event)
+	}
+}
+
+func (c *wsClient) writePump() {
+	ticker := time.NewTicker(pingPeriod)
+	defer func() {
+		ticker.Stop()
+		c.Close()
+	}()
+
+	for {
+		select {
+		case <-c.done:
+			return
+		case event := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.conn.WriteJSON(event); err != nil {
+				return
+			}
+		case <-ticker.C:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// NewClientID 生成连接ID
+func NewClientID() string {
+	buf := make([]byte, 8)
+	rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
@@ -0,0 +1,25 @@
+package relation
+
+import (
+	"Backed/database/dal"
+	"Backed/database/model"
+)
+
+// IsBlocked 判断 blockerID 是否拉黑了 targetID
+func IsBlocked(blockerID, targetID int64) (bool, error) {
+	var count int64
+	err := dal.PostgreSQL.Model(&model.UserBlock{}).
+		Where("blocker_id = ? AND blocked_id = ?", blockerID, targetID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// HasBlockBetween 判断两个用户之间是否存在任意方向的拉黑关系
+// 私聊、好友申请、临时会话以及在线状态等都应当以此为准拒绝交互
+func HasBlockBetween(a, b int64) (bool, error) {
+	var count int64
+	err := dal.PostgreSQL.Model(&model.UserBlock{}).
+		Where("(blocker_id = ? AND blocked_id = ?) OR (blocker_id = ? AND blocked_id = ?)", a, b, b, a).
+		Count(&count).Error
+	return count > 0, err
+}
@@ -0,0 +1,131 @@
+package retention
+
+import (
+	"Backed/database/dal"
+	"Backed/database/model"
+	"Backed/utils/realtime"
+	"log"
+	"time"
+)
+
+const purgeBatch = 1000
+
+func PurgeExpiredTask() {
+	ticker := time.NewTicker(purgeInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		purgeEnvelopes()
+		purgeMessages()
+		purgeAttachments()
+	}
+}
+
+type expiredEnvelope struct {
+	ID              int64
+	RecipientID     int64
+	RecipientDevice int
+}
+
+// purgeEnvelopes 删除已过期或超过服务器上限的密文，并通知接收设备
+func purgeEnvelopes() {
+	now := time.Now()
+	// 服务器上限调小后，之前写入的密文同样按新上限清理
+	cutoff := time.Time{}
+	if maxRetention > 0 {
+		cutoff = now.Add(-maxRetention)
+	}
+
+	for {
+		var expired []expiredEnvelope
+		err := dal.PostgreSQL.Raw(`DELETE FROM envelopes WHERE id IN (
+				SELECT id FROM envelopes
+				WHERE expires_at < ? OR created_at < ?
+				LIMIT ?)
+			RETURNING id, recipient_id, recipient_device`, now, cutoff, purgeBatch).
+			Scan(&expired).Error
+		if err != nil {
+			log.Printf("清理过期消息失败: %s", err)
+			return
+		}
+
+		type device struct {
+			userID   int64
+			deviceID int
+		}
+		grouped := make(map[device][]int64)
+		for _, env := range expired {
+			key := device{env.RecipientID, env.RecipientDevice}
+			grouped[key] = append(grouped[key], env.ID)
+		}
+		for key, ids := range grouped {
+			realtime.SendToUser(key.userID, realtime.NewEvent("e2ee.expired", realtime.H{
+				"device_id": key.deviceID,
+				"ids":       ids,
+			}))
+		}
+
+		if len(expired) < purgeBatch {
+			return
+		}
+	}
+}
+
+// purgeMessages 删除过期消息的元数据，保留时长与密文一致
+func purgeMessages() {
+	now := time.Now()
+	cutoff := time.Time{}
+	if maxRetention > 0 {
+		cutoff = now.Add(-maxRetention)
+	}
+
+	for {
+		expired := dal.PostgreSQL.Model(&model.E2EEMessage{}).Select("id").
+			Where("expires_at < ? OR created_at < ?", now, cutoff).Limit(purgeBatch)
+		result := dal.PostgreSQL.Where("id IN (?)", expired).Delete(&model.E2EEMessage{})
+		if result.Error != nil {
+			log.Printf("清理过期消息记录失败: %s", result.Error)
+			return
+		}
+		if result.RowsAffected < purgeBatch {
+			return
+		}
+	}
+}
+
+type expiredAttachment struct {
+	ID         int64
+	UploaderID int64
+	PeerID     *int64
+}
+
+// purgeAttachments 删除过期的会话附件，文件本身由无引用清理任务回收
+func purgeAttachments() {
+	for {
+		var expired []expiredAttachment
+		err := dal.PostgreSQL.Raw(`DELETE FROM attachments WHERE id IN (
+				SELECT id FROM attachments
+				WHERE expires_at < ?
+				LIMIT ?)
+			RETURNING id, uploader_id, peer_id`, time.Now(), purgeBatch).
+			Scan(&expired).Error
+		if err != nil {
+			log.Printf("清理过期附件失败: %s", err)
+			return
+		}
+
+		for _, att := range expired {
+			event := realtime.NewEvent("attachment.expired", realtime.H{
+				"id": att.ID,
+			})
+			realtime.SendToUser(att.UploaderID, event)
+			if att.PeerID != nil && *att.PeerID != att.UploaderID {
+				realtime.SendToUser(*att.PeerID, event)
+			}
+		}
+
+		if len(expired) < purgeBatch {
+			return
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package retention
+
+import (
+	"Backed/config"
+	"Backed/database/dal"
+	"Backed/database/model"
+	"Backed/utils/realtime"
+	"errors"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Allowed 可选的会话保留时长(秒)，0 表示不自动删除
+var Allowed = []int{0, 3600, 86400, 7 * 86400, 30 * 86400}
+
+var ErrInvalidDuration = errors.New("不支持的保留时长")
+
+var (
+	maxRetention  time.Duration
+	purgeInterval = 5 * time.Minute
+)
+
+// Init 设置服务器最长保留时间和清理间隔
+func Init(cfg config.RetentionConfig) {
+	if cfg.MaxDays > 0 {
+		maxRetention = time.Duration(cfg.MaxDays) * 24 * time.Hour
+	}
+	if cfg.PurgeIntervalMinutes > 0 {
+		purgeInterval = time.Duration(cfg.PurgeIntervalMinutes) * time.Minute
+	}
+}
+
+// MaxSeconds 服务器最长保留时间，0 表示不限制
+func MaxSeconds() int {
+	return int(maxRetention / time.Second)
+}
+
+func pair(a, b int64) (int64, int64) {
+	if a > b {
+		return b, a
+	}
+	return a, b
+}
+
+// Get 会话设置的保留时长(秒)，未设置时为 0
+func Get(a, b int64) (int, error) {
+	low, high := pair(a, b)
+	var setting model.ConversationRetention
+	err := dal.PostgreSQL.Where("user_low = ? AND user_high = ?", low, high).First(&setting).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return setting.Seconds, nil
+}
+
+// Set 修改会话的保留时长，并通知双方的所有在线客户端
+func Set(actorID, peerID int64, seconds int) error {
+	valid := false
+	for _, allowed := range Allowed {
+		if seconds == allowed {
+			valid = true
+			break
+		}
+	}
+	if !valid || (maxRetention > 0 && seconds > MaxSeconds()) {
+		return ErrInvalidDuration
+	}
+
+	low, high := pair(actorID, peerID)
+	setting := model.ConversationRetention{
+		UserLow:   low,
+		UserHigh:  high,
+		Seconds:   seconds,
+		UpdatedBy: actorID,
+	}
+	err := dal.PostgreSQL.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_low"}, {Name: "user_high"}},
+		DoUpdates: clause.AssignmentColumns([]string{"seconds", "updated_by", "updated_at"}),
+	}).Create(&setting).Error
+	if err != nil {
+		return err
+	}
+
+	notify(actorID, peerID, actorID, seconds)
+	if peerID != actorID {
+		notify(peerID, actorID, actorID, seconds)
+	}
+	return nil
+}
+
+func notify(userID, peerID, actorID int64, seconds int) {
+	realtime.SendToUser(userID, realtime.NewEvent("conversation.retention", realtime.H{
+		"peer_id":    strconv.FormatInt(peerID, 10),
+		"seconds":    seconds,
+		"updated_by": strconv.FormatInt(actorID, 10),
+	}))
+}
+
+// ExpiresAt 按会话设置和服务器上限计算新消息的过期时间，不过期时返回 nil
+func ExpiresAt(a, b int64, now time.Time) (*time.Time, error) {
+	seconds, err := Get(a, b)
+	if err != nil {
+		return nil, err
+	}
+
+	ttl := time.Duration(seconds) * time.Second
+	if maxRetention > 0 && (ttl == 0 || ttl > maxRetention) {
+		ttl = maxRetention
+	}
+	if ttl == 0 {
+		return nil, nil
+	}
+	expires := now.Add(ttl)
+	return &expires, nil
+}
+
+// ApplyToAttachment 将附件关联到与 peerID 的会话，随会话的保留时长过期
+func ApplyToAttachment(att *model.Attachment, peerID int64) error {
+	expires, err := ExpiresAt(att.UploaderID, peerID, att.CreatedAt)
+	if err != nil {
+		return err
+	}
+	att.PeerID = &peerID
+	att.ExpiresAt = expires
+	return dal.PostgreSQL.Model(att).Updates(map[string]interface{}{
+		"peer_id":    att.PeerID,
+		"expires_at": att.ExpiresAt,
+	}).Error
+}
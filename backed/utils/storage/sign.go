@@ -0,0 +1,59 @@
+package storage
+
+import (
+	"Backed/config"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+)
+
+var (
+	signingKey []byte
+	urlTTL     = time.Hour
+)
+
+func initSigning(cfg config.StorageConfig) {
+	if cfg.URLTTLMinutes > 0 {
+		urlTTL = time.Duration(cfg.URLTTLMinutes) * time.Minute
+	}
+
+	if cfg.SigningKey != "" {
+		signingKey = []byte(cfg.SigningKey)
+		return
+	}
+
+	// 未配置密钥时使用随机密钥，重启后旧链接失效
+	log.Printf("未配置下载链接签名密钥，将使用随机密钥")
+	signingKey = make([]byte, 32)
+	if _, err := rand.Read(signingKey); err != nil {
+		log.Fatalf("无法生成签名密钥: %s", err)
+	}
+}
+
+// SignDownloadURL 生成附件的限时下载链接
+func SignDownloadURL(attachmentID int64) (string, time.Time) {
+	expires := time.Now().Add(urlTTL)
+	sig := signature(attachmentID, expires.Unix())
+	return fmt.Sprintf("/attachment/download/%d?expires=%d&sig=%s", attachmentID, expires.Unix(), sig), expires
+}
+
+// VerifyDownloadURL 校验下载链接的签名与有效期
+func VerifyDownloadURL(attachmentID int64, expiresRaw, sig string) bool {
+	expires, err := strconv.ParseInt(expiresRaw, 10, 64)
+	if err != nil || time.Now().Unix() > expires {
+		return false
+	}
+	expected := signature(attachmentID, expires)
+	return hmac.Equal([]byte(expected), []byte(sig))
+}
+
+func signature(attachmentID, expires int64) string {
+	mac := hmac.New(sha256.New, signingKey)
+	fmt.Fprintf(mac, "%d:%d", attachmentID, expires)
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
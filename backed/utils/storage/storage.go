@@ -0,0 +1,74 @@
+package storage
+
+import (
+	"Backed/config"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+var ErrNotFound = errors.New("对象不存在")
+
+// Storage 附件存储后端
+type Storage interface {
+	// Put 写入对象，size 未知时传 -1
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Get 读取对象，不存在时返回 ErrNotFound
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete 删除对象，对象不存在不视为错误
+	Delete(ctx context.Context, key string) error
+	// Exists 判断对象是否存在
+	Exists(ctx context.Context, key string) (bool, error)
+}
+
+// Default 全局使用的存储后端，由 Init 设置
+var Default Storage
+
+// Init 根据配置创建存储后端
+func Init(cfg config.StorageConfig) error {
+	initSigning(cfg)
+
+	switch cfg.Backend {
+	case "", "local":
+		dir := cfg.LocalDir
+		if dir == "" {
+			dir = "data/attachments"
+		}
+		local, err := NewLocalStorage(dir)
+		if err != nil {
+			return err
+		}
+		Default = local
+	case "s3":
+		Default = NewS3Storage(S3Options{
+			Endpoint:  cfg.S3Endpoint,
+			Region:    cfg.S3Region,
+			Bucket:    cfg.S3Bucket,
+			AccessKey: cfg.S3AccessKey,
+			SecretKey: cfg.S3SecretKey,
+		})
+	default:
+		return fmt.Errorf("未知的存储后端: %s", cfg.Backend)
+	}
+	return nil
+}
+
+// HashKey 根据内容哈希生成对象键，前两级目录用于分散文件
+func HashKey(hash string) string {
+	return fmt.Sprintf("sha256/%s/%s/%s", hash[:2], hash[2:4], hash)
+}
+
+// validKey 拒绝可能逃逸存储目录的对象键
+func validKey(key string) bool {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return false
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return false
+		}
+	}
+	return true
+}
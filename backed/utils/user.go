@@ -0,0 +1,29 @@
+package utils
+
+import (
+	"Backed/database/dal"
+	"Backed/database/model"
+	"errors"
+
+	"github.com/gin-gonic/gin"
+)
+
+var ErrNoClaims = errors.New("无法获取用户信息")
+
+// GetCurrentUser 获取当前登录用户，优先使用 AuthMiddleware 已加载的用户
+func GetCurrentUser(c *gin.Context) (*model.User, error) {
+	if user, exists := c.Get(userCtxKey); exists {
+		return user.(*model.User), nil
+	}
+
+	claims := GetClaims(c)
+	if claims == nil {
+		return nil, ErrNoClaims
+	}
+
+	var user model.User
+	if err := dal.PostgreSQL.Where("username = ?", claims.Subject).First(&user).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}